@@ -4,17 +4,20 @@ import (
 	"log"
 
 	"github.com/vasyl-ks/TM-software-H11/config"
+	clock "github.com/vasyl-ks/TM-software-H11/internal/clock"
 	consumer "github.com/vasyl-ks/TM-software-H11/internal/consumer"
 	generator "github.com/vasyl-ks/TM-software-H11/internal/generator"
 	hub "github.com/vasyl-ks/TM-software-H11/internal/hub"
 	modelPkg "github.com/vasyl-ks/TM-software-H11/internal/model"
+	queue "github.com/vasyl-ks/TM-software-H11/internal/queue"
 )
 
 /*
-Start loads configuration values, creates an internal channel, and then calls the internal goroutines.
-- Generator produces SensorData, process it into ResultData and then sends it through internalChan.
-- Hub receives ResultData from internalChan, and sends it via UDP to TelemetryLogger.
+Start loads configuration values, creates the internal queues, and then calls the internal goroutines.
+- Generator produces SensorData, process it into ResultData and then sends it through resultQueue.
+- Hub receives ResultData from resultQueue, Event from eventQueue and raw SensorFrame from frameQueue, and sends them via UDP/TCP to TelemetryLogger.
 - Consumer listens for raw JSON datagrams, parses them to ResultData and logs them.
+- Queue reports saturated queues (depth, drops and stalls) periodically.
 The final "select {}" keep the program running indefinitely.
 */
 func Start() {
@@ -26,15 +29,21 @@ func Start() {
 	// Wait for config to finish
 	<-config.Done
 
-	// Creates internal channel of ResultData and Command between Generator and Hub.
-	resultChan := make(chan modelPkg.ResultData)
-	commandChan := make(chan modelPkg.Command)
+	// Set up the simulated clock shared by Generator, Hub and Consumer
+	clock.Configure()
+
+	// Creates internal queues of ResultData, Event, SensorFrame and Command between Generator and Hub.
+	resultQueue := queue.New[modelPkg.ResultData]("main.result")
+	eventQueue := queue.New[modelPkg.Event]("main.event")
+	frameQueue := queue.New[modelPkg.SensorFrame]("main.raw")
+	commandQueue := queue.New[modelPkg.Command]("main.command")
 
 	// Run Generator, Hub and Consumer.
-	go generator.Run(commandChan, resultChan)
+	go generator.Run(commandQueue.Out(), resultQueue, eventQueue, frameQueue)
 	go consumer.Run()
 	<-consumer.Ready // Wait for consumer to initialize UDP&TCP listeners, before Hub tries to connect.
-	go hub.Run(resultChan, commandChan)
+	go hub.Run(resultQueue.Out(), eventQueue.Out(), frameQueue.Out(), commandQueue)
+	go queue.Report()
 
 	select {}
 }
@@ -2,8 +2,12 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -11,23 +15,232 @@ type vehicle struct {
 	VehicleID string `json:"vehicleID"`
 }
 
-type sensor struct {
+/*
+VehicleConfig declares one vehicle.
+Its Sensor is the "sensor" section of the config with the vehicle's own "sensor" overrides applied on top:
+objects are merged field by field, while a map entry (e.g. one noise channel) or a list (e.g. the modes) is replaced as a whole.
+Its Source is its own "source", or else the "source" section, and sets where its SensorData comes from.
+*/
+type VehicleConfig struct {
+	VehicleID string
+	Sensor    SensorConfig
+	Source    SourceConfig
+}
+
+// SourceConfig selects where the SensorData of a vehicle comes from.
+type SourceConfig struct {
+	Type   string             `json:"type"`             // simulator (default) | replay | stub, or a type registered with generator.RegisterSource
+	Path   string             `json:"path,omitempty"`   // replay: recording file or directory, required
+	Loop   bool               `json:"loop,omitempty"`   // replay: start over when the recording ends
+	Values map[string]float64 `json:"values,omitempty"` // stub: the constant reading of each channel, by name; others read 0
+}
+
+// SensorConfig holds the limits and physical models of a simulated vehicle.
+type SensorConfig struct {
 	Interval    time.Duration
-	I           int     `json:"intervalMilliSeconds"`
-	MaxSpeed    float32 `json:"maxSpeed"`
-	MinSpeed    float32 `json:"minSpeed"`
-	MaxPressure float32 `json:"maxPressure"`
-	MinPressure float32 `json:"minPressure"`
-	MaxTemp     float32 `json:"maxTemp"`
-	MinTemp     float32 `json:"minTemp"`
-	EcoMode		float32 `json:"ecoMode"`
-	NormalMode	float32 `json:"normalMode"`
-	SpeedMode	float32 `json:"speedMode"`
+	I           int              `json:"intervalMilliSeconds"`
+	MaxSpeed    float32          `json:"maxSpeed"`
+	MinSpeed    float32          `json:"minSpeed"`
+	MaxPressure float32          `json:"maxPressure"`
+	MinPressure float32          `json:"minPressure"`
+	MaxTemp     float32          `json:"maxTemp"`
+	MinTemp     float32          `json:"minTemp"`
+	Modes       []Mode           `json:"modes"`
+	DefaultMode string           `json:"defaultMode"` // mode a vehicle starts in; defaults to the first mode
+	Dynamics    Dynamics         `json:"dynamics"`
+	Noise       map[string]Noise `json:"noise"`
+	Battery     Battery          `json:"battery"`
+	Thermal     Thermal          `json:"thermal"`
+	Track       Track            `json:"track"`
+}
+
+// Dynamics holds the longitudinal vehicle model parameters, in SI units.
+type Dynamics struct {
+	Mass              float64 `json:"massKg"`
+	MaxTractionForce  float64 `json:"maxTractionForceN"`
+	MaxBrakeForce     float64 `json:"maxBrakeForceN"`
+	DragCoefficient   float64 `json:"dragCoefficient"`
+	FrontalArea       float64 `json:"frontalAreaM2"`
+	AirDensity        float64 `json:"airDensityKgM3"`
+	RollingResistance float64 `json:"rollingResistance"`
+	ThrottleGain      float64 `json:"throttleGain"` // throttle per km/h of speed error, saturated at ±1; the gain of modes without PID gains
+}
+
+// Battery holds the parameters of the simulated battery pack.
+type Battery struct {
+	Capacity             float64 `json:"capacityAh"`
+	MinVoltage           float64 `json:"minVoltageV"` // open-circuit voltage when empty
+	MaxVoltage           float64 `json:"maxVoltageV"` // open-circuit voltage when full
+	MaxCurrent           float64 `json:"maxCurrentA"` // rated discharge current, the most the pack supplies; 0 is unlimited
+	InternalResistance   float64 `json:"internalResistanceOhm"`
+	InitialSoC           float64 `json:"initialSoC"`           // 0..1
+	AuxiliaryCurrent     float64 `json:"auxiliaryCurrentA"`    // draw of the on-board electronics
+	DrivetrainEfficiency float64 `json:"drivetrainEfficiency"` // mechanical / electrical power, 0..1
+	HeatCapacity         float64 `json:"heatCapacityJK"`       // J/K of the whole pack
+	CoolingCoefficient   float64 `json:"coolingCoefficientWK"` // W/K toward ambient
+	AmbientTemp          float64 `json:"ambientTemp"`          // °C
+}
+
+// Thermal holds the parameters of the first-order vehicle temperature model. Its time constants depend on the Mode.
+type Thermal struct {
+	AmbientTemp float64 `json:"ambientTemp"` // °C
+	RisePerKW   float64 `json:"risePerKW"`   // °C above ambient at steady state, per kW of traction power
+}
+
+/*
+Track describes the track a vehicle runs on, in meters from its start.
+A zero length is an open track: position still integrates, but there is no end to brake for.
+*/
+type Track struct {
+	Length       float64   `json:"lengthM"`
+	BrakingZone  float64   `json:"brakingZoneM"`    // final stretch where traction is cut and the vehicle brakes to a stop
+	Deceleration float64   `json:"decelerationMS2"` // planned deceleration toward lower limits and the end; 0 uses half the brake force
+	Segments     []Segment `json:"segments"`
+	Stations     []Station `json:"stations"`
+}
+
+// Segment is a stretch of the Track with a speed limit.
+type Segment struct {
+	Name       string  `json:"name"`
+	Start      float64 `json:"startM"`
+	End        float64 `json:"endM"`
+	SpeedLimit float32 `json:"speedLimitKmh"`
+}
+
+// Station is a named point of the Track.
+type Station struct {
+	Name     string  `json:"name"`
+	Position float64 `json:"positionM"`
+}
+
+// Mode is a driving mode a vehicle can be switched to with the "mode" command.
+type Mode struct {
+	Name              string  `json:"name"`
+	SpeedCap          float32 `json:"speedCap"`             // fraction of maxSpeed the target speed is capped at, (0, 1]
+	GrowthFactor      float32 `json:"growthFactor"`         // how fast pressure grows with speed
+	AccelerationLimit float64 `json:"accelerationLimitMS2"` // m/s², 0 leaves it to the traction force
+	HeatingTau        float64 `json:"heatingTauSeconds"`    // thermal time constant while heating up
+	CoolingTau        float64 `json:"coolingTauSeconds"`    // thermal time constant while cooling down
+	PID               PID     `json:"pid"`                  // speed controller gains; all zero uses dynamics.throttleGain alone
+}
+
+// PID holds the gains of the speed controller, in throttle per km/h of speed error.
+type PID struct {
+	Kp float64 `json:"kp"` // per km/h
+	Ki float64 `json:"ki"` // per km/h·s
+	Kd float64 `json:"kd"` // per km/h/s
+}
+
+// defaultModes are used by a sensor that declares no modes.
+var defaultModes = []Mode{
+	{Name: "eco", SpeedCap: 0.5, GrowthFactor: 0.7, HeatingTau: 90, CoolingTau: 120},
+	{Name: "normal", SpeedCap: 0.8, GrowthFactor: 1.0, HeatingTau: 60, CoolingTau: 120},
+	{Name: "sport", SpeedCap: 1.0, GrowthFactor: 1.3, HeatingTau: 40, CoolingTau: 120},
+}
+
+// Noise describes the error model of one sensor channel. Zero values disable each effect.
+type Noise struct {
+	StdDev     float64 `json:"stdDev"`     // Gaussian white noise
+	DriftRate  float64 `json:"driftRate"`  // random-walk drift, std-dev per √second
+	Bias       float64 `json:"bias"`       // constant offset
+	Resolution float64 `json:"resolution"` // ADC quantization step
+	Hold       time.Duration
+	H          int `json:"holdMilliSeconds"` // sample-and-hold period
+}
+
+type simulation struct {
+	Seed      int64     `json:"seed"`      // seeds the random generator of every vehicle; 0 picks one at startup
+	StartTime time.Time `json:"startTime"` // simulated time at startup; unset uses the wall clock
+	Speed     float64   `json:"speed"`     // simulated seconds per real second; defaults to 1
+	Paused    bool      `json:"paused"`    // start with simulated time frozen, waiting for "resume" or "step"
+}
+
+type replay struct {
+	Path  string  `json:"path"`  // recording file or directory; empty runs the simulated vehicles
+	Speed float64 `json:"speed"` // playback speed relative to the recorded timing; defaults to 1
+	Loop  bool    `json:"loop"`  // start over when the recording ends
 }
 
 type processor struct {
-	Interval time.Duration
-	I        int `json:"intervalMilliSeconds"`
+	Interval    time.Duration
+	I           int            `json:"intervalMilliSeconds"`
+	Percentiles []float64      `json:"percentiles"` // per-channel percentiles of every batch, in (0, 100); defaults to 95 and 99
+	Windows     []WindowConfig `json:"windows"`     // extra aggregation windows, computed alongside the batches
+}
+
+// Types of aggregation window.
+const (
+	WindowTumbling = "tumbling" // back-to-back windows of size
+	WindowSliding  = "sliding"  // every hop, the last size; hop cannot exceed size
+	WindowHopping  = "hopping"  // a window of size starting every hop; a hop above size leaves gaps
+	WindowCount    = "count"    // the last count readings, every hopCount readings
+)
+
+// WindowConfig declares an aggregation window. Its results are tagged with its Name.
+type WindowConfig struct {
+	Name     string `json:"name"` // defaults to the type and size, e.g. "sliding-1s"
+	Type     string `json:"type"`
+	Size     time.Duration
+	S        int `json:"sizeMilliSeconds"`
+	Hop      time.Duration
+	H        int `json:"hopMilliSeconds"` // defaults to the size
+	Count    int `json:"count"`
+	HopCount int `json:"hopCount"` // defaults to count
+}
+
+type alerts struct {
+	Rules []AlertRule `json:"rules"`
+}
+
+// Alert severities.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Sources an alert rule is evaluated on; any other source names a window of processor.windows.
+const (
+	AlertSourceBatch  = "batch"  // the ResultData of every batch
+	AlertSourceSample = "sample" // every raw SensorData
+)
+
+/*
+AlertRule declares a threshold alarm, e.g. "AverageTemperature > 45 for 2s":
+it is raised once Field compares to Threshold by Op for For, and cleared once
+the value is back past Threshold by Hysteresis, so a value hovering at the threshold does not flap.
+*/
+type AlertRule struct {
+	Name       string  `json:"name"`  // defaults to the condition, e.g. "AverageTemperature > 45"
+	Field      string  `json:"field"` // a ResultData field ("AverageTemperature"), a channel statistic ("temperature.p95") or, on samples, a channel ("temperature")
+	Op         string  `json:"op"`    // > | >= | < | <=
+	Threshold  float64 `json:"threshold"`
+	Hysteresis float64 `json:"hysteresis"`
+	For        time.Duration
+	F          int    `json:"forMilliSeconds"` // how long the condition has to hold; 0 raises at once
+	Severity   string `json:"severity"`        // info | warning | critical; defaults to warning
+	Source     string `json:"source"`          // batch | sample | a window name; defaults to batch
+}
+
+/*
+AnomalyConfig tunes the anomaly detector that follows Process. Every checked channel of every vehicle keeps
+an exponentially weighted (EWMA) mean and standard deviation of its batch averages and of their rate of change.
+*/
+type AnomalyConfig struct {
+	ZThreshold  float64            `json:"zThreshold"`  // sensitivity: standard deviations from the baseline that make a value anomalous; 0 disables the z-score checks
+	Alpha       float64            `json:"alpha"`       // EWMA smoothing factor in (0, 1]; defaults to 0.1
+	Warmup      int                `json:"warmup"`      // batches that build a baseline before a channel is checked; defaults to 20
+	StdDevFloor float64            `json:"stdDevFloor"` // lowest standard deviation of a baseline, as a fraction of the channel range; defaults to 0.01
+	Channels    []string           `json:"channels"`    // channels checked; defaults to every channel
+	MaxRate     map[string]float64 `json:"maxRate"`     // largest change per second between batches, by channel; no entry leaves it to the z-score
+}
+
+// RawConfig configures the raw SensorData stream, which forwards every reading in frames alongside the ResultData.
+type RawConfig struct {
+	Enabled    bool `json:"enabled"` // stream every vehicle from startup; the "raw" Command switches it at runtime
+	Frame      time.Duration
+	F          int `json:"frameMilliSeconds"` // readings per frame, by time; defaults to 50
+	MaxSamples int `json:"maxSamples"`        // closes a frame early once it holds this many readings; defaults to 500
 }
 
 type logger struct {
@@ -36,18 +249,55 @@ type logger struct {
 }
 
 type hub struct {
-	UDPPort    int `json:"udpPort"`
-	TCPPort    int `json:"tcpPort"`
-	WSPort     int `json:"wsPort"`
-	BufferSize int `json:"bufferSize"`
+	UDPPort    int          `json:"udpPort"`
+	TCPPort    int          `json:"tcpPort"`
+	WSPort     int          `json:"wsPort"`
+	BufferSize int          `json:"bufferSize"`
+	Sinks      []SinkConfig `json:"sinks"`
+}
+
+// SinkConfig declares one Hub output. Only the fields used by its Type need to be set.
+type SinkConfig struct {
+	Type      string `json:"type"`                // udp | tcp | ws | file | webhook | memory
+	Name      string `json:"name,omitempty"`      // defaults to Type
+	QueueSize int    `json:"queueSize,omitempty"` // defaults to 256
+	Path      string `json:"path,omitempty"`      // file
+	URL       string `json:"url,omitempty"`       // webhook
+}
+
+type pipeline struct {
+	ReportInterval time.Duration
+	R              int                      `json:"reportIntervalMilliSeconds"`
+	Channels       map[string]ChannelConfig `json:"channels"`
+}
+
+// Overflow policies of a pipeline channel.
+const (
+	OverflowBlock      = "block"      // the producer waits for room (unbuffered behaviour)
+	OverflowDropNewest = "dropNewest" // the value being pushed is discarded
+	OverflowDropOldest = "dropOldest" // the oldest queued value is discarded to make room
+)
+
+// ChannelConfig sets the capacity and overflow policy of a named pipeline channel.
+type ChannelConfig struct {
+	Capacity int    `json:"capacity"`
+	Overflow string `json:"overflow"`
 }
 
 // Global config instances
 var Vehicle vehicle
-var Sensor sensor
+var Sensor SensorConfig
+var Vehicles []VehicleConfig
+var Source SourceConfig
+var Simulation simulation
+var Replay replay
 var Processor processor
+var Alerts alerts
+var Anomaly AnomalyConfig
+var Raw RawConfig
 var Logger logger
 var Hub hub
+var Pipeline pipeline
 
 // Exported channel to signal when config finishes loading
 var Done = make(chan struct{})
@@ -68,12 +318,21 @@ func LoadConfig() {
 	decoder := json.NewDecoder(file)
 
 	// Parse it into a temp struct
+	// The sensor sections are kept raw, since every vehicle applies its overrides on top of the default one
 	temp := struct {
-		V   vehicle   `json:"vehicle"`
-		S   sensor    `json:"sensor"`
-		P   processor `json:"processor"`
-		L   logger    `json:"logger"`
-		H   hub       `json:"hub"`
+		V  vehicle         `json:"vehicle"`
+		Vs []vehicleEntry  `json:"vehicles"`
+		S  json.RawMessage `json:"sensor"`
+		So SourceConfig    `json:"source"`
+		Si simulation      `json:"simulation"`
+		Re replay          `json:"replay"`
+		P  processor       `json:"processor"`
+		A  alerts          `json:"alerts"`
+		An AnomalyConfig   `json:"anomaly"`
+		R  RawConfig       `json:"raw"`
+		L  logger          `json:"logger"`
+		H  hub             `json:"hub"`
+		Pi pipeline        `json:"pipeline"`
 	}{}
 	err = decoder.Decode(&temp)
 	if err != nil {
@@ -83,14 +342,365 @@ func LoadConfig() {
 
 	// Copy parsed values into globals
 	Vehicle = temp.V
-	Sensor = temp.S
+	Source = temp.So
+	Simulation = temp.Si
+	Replay = temp.Re
 	Processor = temp.P
+	Alerts = temp.A
+	Anomaly = temp.An
+	Raw = temp.R
 	Logger = temp.L
 	Hub = temp.H
+	Pipeline = temp.Pi
+
+	// Every vehicle builds on the default sensor, so the app cannot start without a valid one
+	Sensor, err = loadSensor("sensor", temp.S, nil)
+	if err != nil {
+		log.Fatalf("[ERROR][Config] Invalid sensor config: %v", err)
+	}
+
+	// Log the seed of every run, so an unseeded run can still be reproduced
+	if Simulation.Seed == 0 {
+		Simulation.Seed = time.Now().UnixNano()
+	}
+	log.Printf("[INFO][Config] Simulation seed: %d.", Simulation.Seed)
+	if Simulation.Speed <= 0 {
+		Simulation.Speed = 1
+	}
+	if Replay.Speed <= 0 {
+		Replay.Speed = 1
+	}
+
+	// A single vehicle, the legacy "vehicle" section, runs when no list is declared
+	if len(temp.Vs) == 0 {
+		temp.Vs = []vehicleEntry{{VehicleID: Vehicle.VehicleID}}
+	}
+	Vehicles = nil
+	seen := make(map[string]bool)
+	for _, v := range temp.Vs {
+		if v.VehicleID == "" || seen[v.VehicleID] {
+			log.Printf("[ERROR][Config] Skipping vehicle with empty or duplicate vehicleID %q.", v.VehicleID)
+			continue
+		}
+		seen[v.VehicleID] = true
+
+		s, err := loadSensor("vehicles["+v.VehicleID+"].sensor", temp.S, v.Sensor)
+		if err != nil {
+			log.Printf("[ERROR][Config] Skipping vehicle %s: %v", v.VehicleID, err)
+			continue
+		}
+		// A vehicle without a source of its own uses the "source" section
+		source := Source
+		if v.Source != nil {
+			source = *v.Source
+		}
+		if source.Type == "" {
+			source.Type = "simulator"
+		}
+		Vehicles = append(Vehicles, VehicleConfig{VehicleID: v.VehicleID, Sensor: s, Source: source})
+	}
 
 	// Derive time.Duration to Seconds
-	Sensor.Interval = time.Duration(Sensor.I) * time.Millisecond
 	Processor.Interval = time.Duration(Processor.I) * time.Millisecond
 
+	// Percentiles outside (0, 100) are dropped; an omitted list reports p95 and p99
+	if Processor.Percentiles == nil {
+		Processor.Percentiles = []float64{95, 99}
+	}
+	percentiles := Processor.Percentiles[:0]
+	for _, p := range Processor.Percentiles {
+		if p <= 0 || p >= 100 {
+			log.Printf("[ERROR][Config] processor.percentiles: %g is not in (0, 100), ignoring it.", p)
+			continue
+		}
+		percentiles = append(percentiles, p)
+	}
+	Processor.Percentiles = percentiles
+	Processor.Windows = validateWindows(Processor.Windows)
+	Alerts.Rules = validateAlertRules(Alerts.Rules, Processor.Windows)
+
+	// The anomaly detector falls back to its defaults on invalid settings
+	if Anomaly.ZThreshold < 0 {
+		log.Printf("[ERROR][Config] anomaly.zThreshold: %g is negative, disabling the z-score checks.", Anomaly.ZThreshold)
+		Anomaly.ZThreshold = 0
+	}
+	if Anomaly.Alpha <= 0 || Anomaly.Alpha > 1 {
+		if Anomaly.Alpha != 0 {
+			log.Printf("[ERROR][Config] anomaly.alpha: %g is not in (0, 1], using 0.1.", Anomaly.Alpha)
+		}
+		Anomaly.Alpha = 0.1
+	}
+	if Anomaly.Warmup <= 0 {
+		Anomaly.Warmup = 20
+	}
+	if Anomaly.StdDevFloor <= 0 {
+		Anomaly.StdDevFloor = 0.01
+	}
+
+	// Raw frames default to 50 ms, at most 500 readings
+	Raw.Frame = time.Duration(Raw.F) * time.Millisecond
+	if Raw.Frame <= 0 {
+		Raw.Frame = 50 * time.Millisecond
+	}
+	if Raw.MaxSamples <= 0 {
+		Raw.MaxSamples = 500
+	}
+	Pipeline.ReportInterval = time.Duration(Pipeline.R) * time.Millisecond
+
+	// Default to the original outputs when no sinks are declared
+	if len(Hub.Sinks) == 0 {
+		Hub.Sinks = []SinkConfig{{Type: "udp"}, {Type: "tcp"}, {Type: "ws"}}
+	}
+
+	// Unknown overflow policies, and dropping ones without capacity, fall back to blocking
+	for name, c := range Pipeline.Channels {
+		switch c.Overflow {
+		case OverflowBlock, OverflowDropNewest, OverflowDropOldest:
+		case "":
+			c.Overflow = OverflowBlock
+		default:
+			log.Printf("[ERROR][Config] Unknown overflow policy %q for channel %s, using %s.", c.Overflow, name, OverflowBlock)
+			c.Overflow = OverflowBlock
+		}
+		if c.Capacity < 0 {
+			c.Capacity = 0
+		}
+		// An unbuffered channel has nothing to drop, so dropping policies need room for one value
+		if c.Capacity == 0 && c.Overflow != OverflowBlock {
+			log.Printf("[ERROR][Config] Overflow policy %s of channel %s requires a capacity of at least 1, using %s.", c.Overflow, name, OverflowBlock)
+			c.Overflow = OverflowBlock
+		}
+		Pipeline.Channels[name] = c
+	}
+
 	close(Done)
 }
+
+// vehicleEntry is a vehicle as written in the config, before its sensor overrides are applied.
+type vehicleEntry struct {
+	VehicleID string          `json:"vehicleID"`
+	Sensor    json.RawMessage `json:"sensor"`
+	Source    *SourceConfig   `json:"source"`
+}
+
+// loadSensor decodes base, then override on top of it, and derives and validates the result. name prefixes the log messages.
+func loadSensor(name string, base, override json.RawMessage) (SensorConfig, error) {
+	var s SensorConfig
+	for _, raw := range []json.RawMessage{base, override} {
+		if len(raw) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return s, err
+		}
+	}
+
+	// Derive time.Duration to Seconds
+	s.Interval = time.Duration(s.I) * time.Millisecond
+	for channel, n := range s.Noise {
+		n.Hold = time.Duration(n.H) * time.Millisecond
+		s.Noise[channel] = n
+	}
+
+	// A massless vehicle cannot be integrated
+	if s.Dynamics.Mass <= 0 {
+		log.Printf("[ERROR][Config] %s.dynamics.massKg must be positive, using 300.", name)
+		s.Dynamics.Mass = 300
+	}
+	if s.Dynamics.ThrottleGain <= 0 {
+		s.Dynamics.ThrottleGain = 0.2
+	}
+
+	// An unconfigured battery never drains nor heats
+	if s.Battery.Capacity <= 0 || s.Battery.HeatCapacity <= 0 || s.Battery.DrivetrainEfficiency <= 0 {
+		log.Printf("[ERROR][Config] %s.battery needs positive capacityAh, heatCapacityJK and drivetrainEfficiency.", name)
+	}
+
+	if err := validateModes(&s); err != nil {
+		return s, fmt.Errorf("%s.modes: %w", name, err)
+	}
+	if err := validateTrack(&s.Track); err != nil {
+		return s, fmt.Errorf("%s.track: %w", name, err)
+	}
+
+	return s, nil
+}
+
+/*
+validateModes checks the mode table of s and fills in its defaults.
+- Without modes, the built-in eco, normal and sport modes are used.
+- Names must be unique and non-empty, speed caps in (0, 1], growth factors positive,
+  and acceleration limits, time constants and PID gains not negative.
+- DefaultMode must name a mode; when empty, it is the first one.
+*/
+func validateModes(s *SensorConfig) error {
+	if len(s.Modes) == 0 {
+		s.Modes = append([]Mode(nil), defaultModes...)
+	}
+
+	seen := make(map[string]bool)
+	for i, m := range s.Modes {
+		key := strings.ToLower(m.Name)
+		switch {
+		case m.Name == "":
+			return fmt.Errorf("mode %d has no name", i)
+		case seen[key]:
+			return fmt.Errorf("duplicate mode %q", m.Name)
+		case m.SpeedCap <= 0 || m.SpeedCap > 1:
+			return fmt.Errorf("mode %q: speedCap must be in (0, 1]", m.Name)
+		case m.GrowthFactor <= 0:
+			return fmt.Errorf("mode %q: growthFactor must be positive", m.Name)
+		case m.AccelerationLimit < 0 || m.HeatingTau < 0 || m.CoolingTau < 0:
+			return fmt.Errorf("mode %q: accelerationLimitMS2 and time constants cannot be negative", m.Name)
+		case m.PID.Kp < 0 || m.PID.Ki < 0 || m.PID.Kd < 0:
+			return fmt.Errorf("mode %q: pid gains cannot be negative", m.Name)
+		}
+		seen[key] = true
+	}
+
+	if s.DefaultMode == "" {
+		s.DefaultMode = s.Modes[0].Name
+	}
+	if !seen[strings.ToLower(s.DefaultMode)] {
+		return fmt.Errorf("unknown defaultMode %q", s.DefaultMode)
+	}
+	return nil
+}
+
+/*
+validateTrack checks the track t and sorts its segments and stations by position.
+- Length, braking zone and deceleration cannot be negative, and the braking zone cannot exceed the length.
+- Segments must start before they end, have a positive speed limit and, on a closed track, lie within its length.
+- Stations must lie on the track.
+*/
+func validateTrack(t *Track) error {
+	switch {
+	case t.Length < 0 || t.BrakingZone < 0 || t.Deceleration < 0:
+		return errors.New("lengthM, brakingZoneM and decelerationMS2 cannot be negative")
+	case t.BrakingZone > t.Length:
+		return errors.New("brakingZoneM cannot exceed lengthM")
+	}
+
+	for i, seg := range t.Segments {
+		switch {
+		case seg.Start < 0 || seg.End <= seg.Start:
+			return fmt.Errorf("segment %d: startM cannot be negative and must be before endM", i)
+		case t.Length > 0 && seg.End > t.Length:
+			return fmt.Errorf("segment %d: endM beyond the track length", i)
+		case seg.SpeedLimit <= 0:
+			return fmt.Errorf("segment %d: speedLimitKmh must be positive", i)
+		}
+	}
+	for _, st := range t.Stations {
+		if st.Position < 0 || (t.Length > 0 && st.Position > t.Length) {
+			return fmt.Errorf("station %q: positionM is off the track", st.Name)
+		}
+	}
+
+	sort.SliceStable(t.Segments, func(i, j int) bool { return t.Segments[i].Start < t.Segments[j].Start })
+	sort.SliceStable(t.Stations, func(i, j int) bool { return t.Stations[i].Position < t.Stations[j].Position })
+	return nil
+}
+
+// validateWindows fills in the defaults of windows and drops, with an error, the ones that cannot be computed.
+func validateWindows(windows []WindowConfig) []WindowConfig {
+	var valid []WindowConfig
+	seen := make(map[string]bool)
+	for i, w := range windows {
+		w.Size = time.Duration(w.S) * time.Millisecond
+		w.Hop = time.Duration(w.H) * time.Millisecond
+		if w.Hop <= 0 {
+			w.Hop = w.Size
+		}
+		if w.HopCount <= 0 {
+			w.HopCount = w.Count
+		}
+
+		var err error
+		switch w.Type {
+		case WindowTumbling, WindowSliding, WindowHopping:
+			switch {
+			case w.Size <= 0:
+				err = errors.New("sizeMilliSeconds must be positive")
+			case w.Type == WindowTumbling && w.Hop != w.Size:
+				err = errors.New("a tumbling window cannot set hopMilliSeconds")
+			case w.Type == WindowSliding && w.Hop > w.Size:
+				err = errors.New("hopMilliSeconds cannot exceed sizeMilliSeconds")
+			}
+			if w.Name == "" {
+				w.Name = fmt.Sprintf("%s-%s", w.Type, w.Size)
+			}
+		case WindowCount:
+			if w.Count <= 0 {
+				err = errors.New("count must be positive")
+			}
+			if w.Name == "" {
+				w.Name = fmt.Sprintf("%s-%d", w.Type, w.Count)
+			}
+		default:
+			err = fmt.Errorf("unknown type %q", w.Type)
+		}
+		if err == nil && seen[w.Name] {
+			err = fmt.Errorf("duplicate name %q", w.Name)
+		}
+		if err != nil {
+			log.Printf("[ERROR][Config] processor.windows[%d]: %v, ignoring it.", i, err)
+			continue
+		}
+
+		seen[w.Name] = true
+		valid = append(valid, w)
+	}
+	return valid
+}
+
+/*
+validateAlertRules fills in the defaults of alert rules and drops the invalid ones, logging why.
+Whether Field names a value of its source is checked by the generator, which reads it.
+*/
+func validateAlertRules(rules []AlertRule, windows []WindowConfig) []AlertRule {
+	var valid []AlertRule
+	seen := make(map[string]bool)
+	for i, r := range rules {
+		r.For = time.Duration(r.F) * time.Millisecond
+		if r.Severity == "" {
+			r.Severity = SeverityWarning
+		}
+		if r.Source == "" {
+			r.Source = AlertSourceBatch
+		}
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("%s %s %g", r.Field, r.Op, r.Threshold)
+		}
+
+		var err error
+		switch {
+		case r.Field == "":
+			err = errors.New("field is required")
+		case r.Op != ">" && r.Op != ">=" && r.Op != "<" && r.Op != "<=":
+			err = fmt.Errorf("unknown op %q", r.Op)
+		case r.Hysteresis < 0 || r.For < 0:
+			err = errors.New("hysteresis and forMilliSeconds cannot be negative")
+		case r.Severity != SeverityInfo && r.Severity != SeverityWarning && r.Severity != SeverityCritical:
+			err = fmt.Errorf("unknown severity %q", r.Severity)
+		case seen[r.Name]:
+			err = fmt.Errorf("duplicate name %q", r.Name)
+		}
+		if err == nil && r.Source != AlertSourceBatch && r.Source != AlertSourceSample {
+			err = fmt.Errorf("unknown source %q", r.Source)
+			for _, w := range windows {
+				if w.Name == r.Source {
+					err = nil
+				}
+			}
+		}
+		if err != nil {
+			log.Printf("[ERROR][Config] alerts.rules[%d]: %v, ignoring it.", i, err)
+			continue
+		}
+
+		seen[r.Name] = true
+		valid = append(valid, r)
+	}
+	return valid
+}
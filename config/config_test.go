@@ -0,0 +1,113 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestValidateModes(t *testing.T) {
+	normal := Mode{Name: "normal", SpeedCap: 0.8, GrowthFactor: 1}
+	with := func(change func(*Mode)) Mode {
+		m := normal
+		change(&m)
+		return m
+	}
+
+	tests := []struct {
+		name        string
+		modes       []Mode
+		defaultMode string
+		wantDefault string
+		wantModes   int
+		err         bool
+	}{
+		{name: "built-in modes", wantDefault: "eco", wantModes: 3},
+		{name: "first mode is the default", modes: []Mode{normal, {Name: "sport", SpeedCap: 1, GrowthFactor: 1.3}}, wantDefault: "normal", wantModes: 2},
+		{name: "default matched case-insensitively", modes: []Mode{normal}, defaultMode: "NORMAL", wantDefault: "NORMAL", wantModes: 1},
+		{name: "unknown default", modes: []Mode{normal}, defaultMode: "sport", err: true},
+		{name: "no name", modes: []Mode{with(func(m *Mode) { m.Name = "" })}, err: true},
+		{name: "duplicate name", modes: []Mode{normal, with(func(m *Mode) { m.Name = "Normal" })}, err: true},
+		{name: "zero speed cap", modes: []Mode{with(func(m *Mode) { m.SpeedCap = 0 })}, err: true},
+		{name: "speed cap above 1", modes: []Mode{with(func(m *Mode) { m.SpeedCap = 1.5 })}, err: true},
+		{name: "zero growth factor", modes: []Mode{with(func(m *Mode) { m.GrowthFactor = 0 })}, err: true},
+		{name: "negative acceleration limit", modes: []Mode{with(func(m *Mode) { m.AccelerationLimit = -1 })}, err: true},
+		{name: "negative time constant", modes: []Mode{with(func(m *Mode) { m.CoolingTau = -1 })}, err: true},
+		{name: "negative pid gain", modes: []Mode{with(func(m *Mode) { m.PID.Ki = -0.1 })}, err: true},
+		{name: "zero limits and gains", modes: []Mode{with(func(m *Mode) { m.AccelerationLimit, m.HeatingTau, m.PID = 0, 0, PID{} })}, wantDefault: "normal", wantModes: 1},
+	}
+
+	for _, tt := range tests {
+		s := SensorConfig{Modes: tt.modes, DefaultMode: tt.defaultMode}
+		err := validateModes(&s)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: validateModes succeeded, want an error", tt.name)
+			}
+			continue
+		}
+		if err != nil || s.DefaultMode != tt.wantDefault || len(s.Modes) != tt.wantModes {
+			t.Errorf("%s: %d modes, default %q, error %v, want %d modes, default %q", tt.name, len(s.Modes), s.DefaultMode, err, tt.wantModes, tt.wantDefault)
+		}
+	}
+
+	// The built-in modes are copied, so a sensor cannot change them for the others
+	s := SensorConfig{}
+	validateModes(&s)
+	s.Modes[0].SpeedCap = 0.1
+	if defaultModes[0].SpeedCap == 0.1 {
+		t.Error("changing the modes of a sensor changed the built-in modes")
+	}
+}
+
+func TestLoadSensor(t *testing.T) {
+	base := `{"intervalMilliSeconds": 100, "maxSpeed": 150, "minSpeed": 0, "dynamics": {"massKg": 300, "maxTractionForceN": 2000},
+		"noise": {"speed": {"stdDev": 0.5, "holdMilliSeconds": 20}}, "battery": {"capacityAh": 10, "heatCapacityJK": 1000, "drivetrainEfficiency": 0.9}}`
+
+	tests := []struct {
+		name     string
+		override string
+		check    func(SensorConfig) bool
+		err      bool
+	}{
+		{name: "no override", check: func(s SensorConfig) bool {
+			return s.Interval == 100*time.Millisecond && s.MaxSpeed == 150 && s.Dynamics.ThrottleGain == 0.2 && s.Noise["speed"].Hold == 20*time.Millisecond && s.DefaultMode == "eco"
+		}},
+		{name: "overridden fields only", override: `{"maxSpeed": 90, "intervalMilliSeconds": 50}`, check: func(s SensorConfig) bool {
+			return s.MaxSpeed == 90 && s.Interval == 50*time.Millisecond && s.Dynamics.Mass == 300 && s.Battery.Capacity == 10
+		}},
+		{name: "nested sections merged", override: `{"dynamics": {"massKg": 500}, "noise": {"pressure": {"stdDev": 0.1}}}`, check: func(s SensorConfig) bool {
+			return s.Dynamics.Mass == 500 && s.Dynamics.MaxTractionForce == 2000 && s.Noise["speed"].StdDev == 0.5 && s.Noise["pressure"].StdDev == 0.1
+		}},
+		{name: "massless vehicle", override: `{"dynamics": {"massKg": 0}}`, check: func(s SensorConfig) bool { return s.Dynamics.Mass == 300 }},
+		{name: "own modes", override: `{"modes": [{"name": "race", "speedCap": 1, "growthFactor": 1.5}]}`, check: func(s SensorConfig) bool {
+			return len(s.Modes) == 1 && s.DefaultMode == "race"
+		}},
+		{name: "invalid modes", override: `{"defaultMode": "warp"}`, err: true},
+		{name: "invalid JSON", override: `{"maxSpeed": "fast"}`, err: true},
+	}
+
+	for _, tt := range tests {
+		var override json.RawMessage
+		if tt.override != "" {
+			override = json.RawMessage(tt.override)
+		}
+		s, err := loadSensor("test", json.RawMessage(base), override)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: loadSensor succeeded, want an error", tt.name)
+			}
+			continue
+		}
+		if err != nil || !tt.check(s) {
+			t.Errorf("%s: got %+v, error %v", tt.name, s, err)
+		}
+	}
+
+	// Overrides of one vehicle do not leak into the base of the next
+	a, _ := loadSensor("a", json.RawMessage(base), json.RawMessage(`{"noise": {"temperature": {"bias": 1}}}`))
+	b, _ := loadSensor("b", json.RawMessage(base), nil)
+	if _, ok := b.Noise["temperature"]; ok || len(a.Noise) != 2 {
+		t.Errorf("noise of a %v and b %v, want the override in a only", a.Noise, b.Noise)
+	}
+}
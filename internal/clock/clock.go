@@ -0,0 +1,221 @@
+package clock
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+Clock is the time source of the simulation.
+Its time advances with the wall clock multiplied by its speed, and it can be paused, resumed
+and stepped forward manually. At speed 1, never paused nor stepped, it reads like time.Now.
+- Tickers and Timers created from a Clock follow its time, so at speed 10 a 1s Ticker fires every 100ms of wall time.
+- While paused, Tickers and Timers only fire when the Clock is stepped past their deadline.
+*/
+type Clock struct {
+	mu      sync.Mutex
+	base    time.Time // clock time at anchor
+	anchor  time.Time // wall time at which base was taken
+	speed   float64
+	paused  bool
+	changed chan struct{} // closed on every change, so waiting Tickers and Timers recompute their deadline
+}
+
+// State is a snapshot of a Clock.
+type State struct {
+	Now    time.Time `json:"now"`
+	Speed  float64   `json:"speed"`
+	Paused bool      `json:"paused"`
+}
+
+// New returns a running Clock that starts at start (the wall clock when zero) and advances at speed× real time.
+func New(start time.Time, speed float64) *Clock {
+	now := time.Now().Local()
+	if start.IsZero() {
+		start = now
+	}
+	return &Clock{
+		base:    start,
+		anchor:  now,
+		speed:   speed,
+		changed: make(chan struct{}),
+	}
+}
+
+func (c *Clock) nowLocked() time.Time {
+	if c.paused {
+		return c.base
+	}
+	return c.base.Add(time.Duration(float64(time.Since(c.anchor)) * c.speed))
+}
+
+// rebase moves the anchor to the current wall time, before the speed or pause state changes.
+func (c *Clock) rebase() {
+	c.base = c.nowLocked()
+	c.anchor = time.Now()
+}
+
+// notify wakes every waiting Ticker and Timer.
+func (c *Clock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Now returns the current time of the Clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nowLocked()
+}
+
+// Since returns the Clock time elapsed since t.
+func (c *Clock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Until returns the Clock time left until t.
+func (c *Clock) Until(t time.Time) time.Duration {
+	return t.Sub(c.Now())
+}
+
+// Pause freezes the Clock.
+func (c *Clock) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rebase()
+	c.paused = true
+	c.notify()
+}
+
+// Resume lets a paused Clock advance again from where it stopped.
+func (c *Clock) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rebase()
+	c.paused = false
+	c.notify()
+}
+
+// Step moves the Clock forward by d at once, firing every Ticker and Timer due meanwhile.
+func (c *Clock) Step(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rebase()
+	c.base = c.base.Add(d)
+	c.notify()
+}
+
+// SetSpeed changes how many times faster than real time the Clock advances.
+func (c *Clock) SetSpeed(speed float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rebase()
+	c.speed = speed
+	c.notify()
+}
+
+// State returns a snapshot of the Clock.
+func (c *Clock) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return State{Now: c.nowLocked(), Speed: c.speed, Paused: c.paused}
+}
+
+// wait blocks until the Clock reaches deadline and reports true, or reports false once stop is closed.
+func (c *Clock) wait(deadline time.Time, stop <-chan struct{}) bool {
+	for {
+		c.mu.Lock()
+		now, changed := c.nowLocked(), c.changed
+		paused, speed := c.paused, c.speed
+		c.mu.Unlock()
+
+		if !now.Before(deadline) {
+			return true
+		}
+
+		// A stopped Clock only moves when it changes
+		if paused || speed <= 0 {
+			select {
+			case <-changed:
+				continue
+			case <-stop:
+				return false
+			}
+		}
+
+		timer := time.NewTimer(time.Duration(float64(deadline.Sub(now)) / speed))
+		select {
+		case <-timer.C:
+		case <-changed:
+			timer.Stop()
+		case <-stop:
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// Ticker delivers the Clock time on C every period of Clock time. Like time.Ticker, it drops ticks for slow receivers.
+type Ticker struct {
+	C    <-chan time.Time
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewTicker returns a Ticker that fires every d of Clock time. It panics if d is not positive.
+func (c *Clock) NewTicker(d time.Duration) *Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	ch := make(chan time.Time, 1)
+	t := &Ticker{C: ch, stop: make(chan struct{})}
+
+	go func() {
+		next := c.Now().Add(d)
+		for c.wait(next, t.stop) {
+			select {
+			case ch <- next:
+			default:
+			}
+
+			// Skip the ticks a step or a slow receiver left behind
+			next = next.Add(d)
+			if now := c.Now(); !next.After(now) {
+				next = next.Add((now.Sub(next)/d + 1) * d)
+			}
+		}
+	}()
+	return t
+}
+
+// Stop turns off the Ticker.
+func (t *Ticker) Stop() {
+	t.once.Do(func() { close(t.stop) })
+}
+
+// Timer calls a function once, after a duration of Clock time.
+type Timer struct {
+	stop  chan struct{}
+	once  sync.Once
+	fired atomic.Bool
+}
+
+// AfterFunc calls f in its own goroutine once d of Clock time has elapsed.
+func (c *Clock) AfterFunc(d time.Duration, f func()) *Timer {
+	t := &Timer{stop: make(chan struct{})}
+	deadline := c.Now().Add(d)
+
+	go func() {
+		if c.wait(deadline, t.stop) && t.fired.CompareAndSwap(false, true) {
+			f()
+		}
+	}()
+	return t
+}
+
+// Stop prevents the Timer from firing and reports whether it did so.
+func (t *Timer) Stop() bool {
+	t.once.Do(func() { close(t.stop) })
+	return t.fired.CompareAndSwap(false, true)
+}
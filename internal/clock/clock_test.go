@@ -0,0 +1,79 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestPausedClockOnlyMovesWhenStepped(t *testing.T) {
+	c := New(epoch, 1)
+	c.Pause()
+	start := c.Now()
+
+	ticker := c.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	select {
+	case <-ticker.C:
+		t.Fatal("ticker fired while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if !c.Now().Equal(start) {
+		t.Fatalf("paused clock moved from %s to %s", start, c.Now())
+	}
+
+	c.Step(90 * time.Second)
+	select {
+	case tick := <-ticker.C:
+		if want := start.Add(time.Minute); !tick.Equal(want) {
+			t.Errorf("tick = %s, want %s", tick, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ticker did not fire after stepping past its period")
+	}
+	if got, want := c.Now(), start.Add(90*time.Second); !got.Equal(want) {
+		t.Errorf("Now() = %s, want %s", got, want)
+	}
+}
+
+func TestFastClockFiresTickersSooner(t *testing.T) {
+	c := New(epoch, 1000)
+	ticker := c.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	select {
+	case <-ticker.C:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("1s ticker at 1000x did not fire within 500ms")
+	}
+}
+
+func TestStoppedTimerNeverFires(t *testing.T) {
+	c := New(epoch, 1)
+	c.Pause()
+
+	fired := make(chan struct{}, 2)
+	stopped := c.AfterFunc(time.Second, func() { fired <- struct{}{} })
+	kept := c.AfterFunc(time.Second, func() { fired <- struct{}{} })
+
+	if !stopped.Stop() {
+		t.Fatal("Stop() = false for a pending timer")
+	}
+	c.Step(time.Second)
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("timer did not fire after stepping to its deadline")
+	}
+	select {
+	case <-fired:
+		t.Fatal("stopped timer fired")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if kept.Stop() {
+		t.Error("Stop() = true for a timer that already fired")
+	}
+}
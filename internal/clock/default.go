@@ -0,0 +1,86 @@
+package clock
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+// Default is the Clock shared by the generator, the hub and the consumer.
+var Default = New(time.Time{}, 1)
+
+// Configure replaces Default with a Clock set up from config.Simulation. Call it once, before starting the pipeline.
+func Configure() {
+	Default = New(config.Simulation.StartTime, config.Simulation.Speed)
+	if config.Simulation.Paused {
+		Default.Pause()
+	}
+	log.Printf("[INFO][Clock] Running at %gx, paused: %t.", config.Simulation.Speed, config.Simulation.Paused)
+}
+
+// Now returns the current time of Default.
+func Now() time.Time { return Default.Now() }
+
+// Since returns the Default time elapsed since t.
+func Since(t time.Time) time.Duration { return Default.Since(t) }
+
+// Until returns the Default time left until t.
+func Until(t time.Time) time.Duration { return Default.Until(t) }
+
+// NewTicker returns a Ticker of Default.
+func NewTicker(d time.Duration) *Ticker { return Default.NewTicker(d) }
+
+// AfterFunc calls f once d of Default time has elapsed.
+func AfterFunc(d time.Duration, f func()) *Timer { return Default.AfterFunc(d, f) }
+
+/*
+Handle applies a clock Command to Default and reports whether cmd was one.
+- "pause" → freezes simulated time.
+- "resume" → lets it advance again.
+- "step" → moves it forward by params, a Go duration ("500ms") or a number of seconds.
+- "speed" → runs it params times faster than real time.
+*/
+func Handle(cmd model.Command) bool {
+	switch strings.ToLower(cmd.Action) {
+	case "pause":
+		Default.Pause()
+		log.Println("[INFO][Clock] Paused.")
+	case "resume":
+		Default.Resume()
+		log.Println("[INFO][Clock] Resumed.")
+	case "step":
+		d, err := duration(cmd.Params)
+		if err != nil || d <= 0 {
+			log.Printf("[ERROR][Clock] Invalid step %v.", cmd.Params)
+			return true
+		}
+		Default.Step(d)
+		log.Printf("[INFO][Clock] Stepped %s.", d)
+	case "speed":
+		speed, ok := cmd.Params.(float64)
+		if !ok || speed <= 0 {
+			log.Printf("[ERROR][Clock] Invalid speed %v.", cmd.Params)
+			return true
+		}
+		Default.SetSpeed(speed)
+		log.Printf("[INFO][Clock] Running at %gx.", speed)
+	default:
+		return false
+	}
+	return true
+}
+
+// duration reads a Go duration string or a number of seconds.
+func duration(params interface{}) (time.Duration, error) {
+	switch v := params.(type) {
+	case string:
+		return time.ParseDuration(v)
+	case float64:
+		return time.Duration(v * float64(time.Second)), nil
+	}
+	return 0, fmt.Errorf("unsupported duration %v", params)
+}
@@ -4,24 +4,27 @@ import (
 	"log"
 
 	"github.com/vasyl-ks/TM-software-H11/internal/model"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
 )
 
 /*
-Consumer initializes the byteChan and jsonChan channels, and calls the Listen, Parse and Log goroutines.
-- Listen runs independently, listens for UDP datagrams and sends it through byteChan.
-- Parse receives a JSON from byteChan, parses it to ResultData and sends it through resultChan.
-- Log receives a ResultData from resultChan and logs it.
+Consumer initializes the byteQueue, resultQueue, commandQueue, eventQueue and frameQueue queues, and calls the Listen, Parse and Log goroutines.
+- Listen runs independently, listens for UDP datagrams and sends it through byteQueue.
+- Parse receives a JSON from byteQueue, parses it to ResultData, Command, Event or SensorFrame and sends it through resultQueue, commandQueue, eventQueue or frameQueue.
+- Log receives a ResultData, Command, Event or SensorFrame from its queue and logs it.
 */
 func Run() {
 	defer log.Println("[INFO][Consumer] Running.")
 
-	// Create unbuffered channels.
-	byteChan := make(chan []byte)
-	resultChan := make(chan model.ResultData)
-	commandChan := make(chan model.Command)
+	// Create bounded queues.
+	byteQueue := queue.New[[]byte]("consumer.bytes")
+	resultQueue := queue.New[model.ResultData]("consumer.result")
+	commandQueue := queue.New[model.Command]("consumer.command")
+	eventQueue := queue.New[model.Event]("consumer.event")
+	frameQueue := queue.New[model.SensorFrame]("consumer.raw")
 
 	// Launch concurrent goroutines.
-	go Listen(byteChan)
-	go Parse(byteChan, resultChan, commandChan)
-	go Log(resultChan, commandChan)
+	go Listen(byteQueue)
+	go Parse(byteQueue.Out(), resultQueue, commandQueue, eventQueue, frameQueue)
+	go Log(resultQueue.Out(), commandQueue.Out(), eventQueue.Out(), frameQueue.Out())
 }
@@ -1,21 +1,25 @@
 package consumer
 
 import (
+	"bufio"
 	"fmt"
-	"io"
 	"log"
 	"net"
 
 	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
 )
 
 var Ready = make(chan struct{})
 
+// maxTCPMessageSize bounds a single newline-delimited TCP message.
+const maxTCPMessageSize = 1 << 20
+
 /*
 Listen binds a UDP socket on config.Sender.ClientPort and forwards incoming datagrams to out.
 - Copies each datagram into a new slice to avoid buffer reuse.
 */
-func Listen(outChan chan<- []byte) {
+func Listen(outQueue *queue.Queue[[]byte]) {
 	addrUDP := fmt.Sprintf("127.0.0.1:%d", config.Hub.UDPPort)
 	addrTCP := fmt.Sprintf("127.0.0.1:%d", config.Hub.TCPPort)
 
@@ -54,7 +58,7 @@ func Listen(outChan chan<- []byte) {
 			}
 			payload := make([]byte, n)
 			copy(payload, buf[:n])
-			outChan <- payload
+			outQueue.Push(payload)
 		}
 	}()
 
@@ -67,18 +71,17 @@ func Listen(outChan chan<- []byte) {
 			return
 		}
 		defer conn.Close()
-		buf := make([]byte, config.Hub.BufferSize)
-		for {
-			n, err := conn.Read(buf)
-			if err != nil {
-				if err != io.EOF {
-					fmt.Printf("[ERROR][Consumer][Listen] Error reading TCP: %v\n", err)
-				}
-				break
-			}
-			payload := make([]byte, n)
-			copy(payload, buf[:n])
-			outChan <- payload
+
+		// Messages are newline-delimited, so split the stream on lines rather than on reads
+		scanner := bufio.NewScanner(conn)
+		scanner.Buffer(make([]byte, config.Hub.BufferSize), maxTCPMessageSize)
+		for scanner.Scan() {
+			payload := make([]byte, len(scanner.Bytes()))
+			copy(payload, scanner.Bytes())
+			outQueue.Push(payload)
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Printf("[ERROR][Consumer][Listen] Error reading TCP: %v\n", err)
 		}
 	}()
 }
@@ -1,13 +1,16 @@
 package consumer
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/clock"
 	"github.com/vasyl-ks/TM-software-H11/internal/model"
 )
 
@@ -16,6 +19,20 @@ type Loggers struct {
 	Main    *log.Logger
 	Data    *log.Logger
 	Command *log.Logger
+	Events  map[string]*log.Logger // by Event type, created on first use
+	Raw     *log.Logger            // raw SensorFrames, created on first use
+	dir     string
+	files   []*os.File
+}
+
+// eventDirs maps an Event type to the subdirectory its log is written to; other types use "<type>s".
+var eventDirs = map[string]string{
+	model.EventFault:    "faults",
+	model.EventState:    "states",
+	model.EventRejected: "rejections",
+	model.EventAlert:    "alerts",
+	model.EventAnomaly:  "anomalies",
+	model.EventTrip:     "trips",
 }
 
 // Helper function to create a logger for a given subdirectory and prefix
@@ -37,19 +54,128 @@ func createLogger(baseDir, subDir, prefix string) (*log.Logger, *os.File, error)
 	return logger, file, nil
 }
 
+// Helper function to create the main, data and command loggers under baseDir
+func openLoggers(baseDir string) (*Loggers, error) {
+	loggers := &Loggers{Events: make(map[string]*log.Logger), dir: baseDir}
+
+	for _, l := range []struct {
+		target         **log.Logger
+		subDir, prefix string
+	}{
+		{&loggers.Main, "", "log"},
+		{&loggers.Data, "data", "data"},
+		{&loggers.Command, "commands", "command"},
+	} {
+		logger, file, err := createLogger(baseDir, l.subDir, l.prefix)
+		if err != nil {
+			loggers.Close()
+			return nil, err
+		}
+		*l.target = logger
+		loggers.files = append(loggers.files, file)
+	}
+
+	return loggers, nil
+}
+
+// Helper function to return the logger of an Event type, creating its file on first use
+func (l *Loggers) event(eventType string) (*log.Logger, error) {
+	if logger, ok := l.Events[eventType]; ok {
+		return logger, nil
+	}
+
+	dir, ok := eventDirs[eventType]
+	if !ok {
+		dir = eventType + "s"
+	}
+	logger, file, err := createLogger(l.dir, dir, eventType)
+	if err != nil {
+		return nil, err
+	}
+	l.Events[eventType] = logger
+	l.files = append(l.files, file)
+	return logger, nil
+}
+
+// Helper function to return the raw logger, creating its file on first use
+func (l *Loggers) raw() (*log.Logger, error) {
+	if l.Raw != nil {
+		return l.Raw, nil
+	}
+
+	logger, file, err := createLogger(l.dir, "raw", "raw")
+	if err != nil {
+		return nil, err
+	}
+	l.Raw = logger
+	l.files = append(l.files, file)
+	return logger, nil
+}
+
+// Helper function to close every file of the group
+func (l *Loggers) Close() {
+	for _, file := range l.files {
+		file.Close()
+	}
+}
+
 // Helper function to write a ResultData
-func writeResult(loggers Loggers, r model.ResultData) {
+func writeResult(loggers *Loggers, r model.ResultData) {
+	if r.Stale {
+		writeGap(loggers, r)
+		return
+	}
+
 	msg := fmt.Sprintf(
-		"[DATA] Created at %s, Processed at %s, Logged at %s | "+
+		"[DATA] Vehicle: %s | Created at %s, Processed at %s, Logged at %s | "+
 			"AvgSpeed: %5.2f, MinSpeed: %5.2f, MaxSpeed: %5.2f | "+
 			"AvgTemp: %5.2f, MinTemp: %5.2f, MaxTemp: %5.2f | "+
-			"AvgPressure: %4.2f, MinPressure: %4.2f, MaxPressure: %4.2f",
+			"AvgPressure: %4.2f, MinPressure: %4.2f, MaxPressure: %4.2f | "+
+			"AvgVoltage: %6.2f, MinVoltage: %6.2f, MaxVoltage: %6.2f | "+
+			"AvgCurrent: %6.2f, MinCurrent: %6.2f, MaxCurrent: %6.2f | "+
+			"AvgSoC: %5.2f, MinSoC: %5.2f, MaxSoC: %5.2f | "+
+			"AvgBattTemp: %5.2f, MinBattTemp: %5.2f, MaxBattTemp: %5.2f | "+
+			"Position: %8.1f, DistanceToEnd: %8.1f",
+		r.VehicleID,
 		r.CreatedAt.Format("15:04:05.000000"),
 		r.ProcessedAt.Format("15:04:05.000000"),
-		time.Now().Local().Format("15:04:05.000000"),
+		clock.Now().Format("15:04:05.000000"),
 		r.AverageSpeed, r.MinimumSpeed, r.MaximumSpeed,
 		r.AverageTemperature, r.MinimumTemperature, r.MaximumTemperature,
 		r.AveragePressure, r.MinimumPressure, r.MaximumPressure,
+		r.AverageBatteryVoltage, r.MinimumBatteryVoltage, r.MaximumBatteryVoltage,
+		r.AverageBatteryCurrent, r.MinimumBatteryCurrent, r.MaximumBatteryCurrent,
+		r.AverageBatterySoC, r.MinimumBatterySoC, r.MaximumBatterySoC,
+		r.AverageBatteryTemperature, r.MinimumBatteryTemperature, r.MaximumBatteryTemperature,
+		r.Position, r.DistanceToEnd,
+	)
+
+	if r.Window != "" {
+		msg += " | Window: " + r.Window
+	}
+	if r.TripID != "" {
+		msg += " | Trip: " + r.TripID
+	}
+	msg += fmt.Sprintf(" | Samples: %d, Expected: %.1f, OutOfRange: %d", r.Count, r.ExpectedCount, r.OutOfRangeCount)
+
+	// The distribution of every channel follows as JSON, too wide for columns
+	if stats, err := json.Marshal(r.Stats); err == nil {
+		msg += " | Stats: " + string(stats)
+	}
+
+	loggers.Main.Println(msg)
+	loggers.Data.Println(msg)
+}
+
+// Helper function to write a Stale ResultData, emitted while no reading arrived
+func writeGap(loggers *Loggers, r model.ResultData) {
+	msg := fmt.Sprintf(
+		"[GAP] Vehicle: %s | Window ends at %s, Logged at %s | No readings since %s | Expected: %.1f",
+		r.VehicleID,
+		r.ProcessedAt.Format("15:04:05.000000"),
+		clock.Now().Format("15:04:05.000000"),
+		r.CreatedAt.Format("15:04:05.000000"),
+		r.ExpectedCount,
 	)
 
 	loggers.Main.Println(msg)
@@ -57,27 +183,97 @@ func writeResult(loggers Loggers, r model.ResultData) {
 }
 
 // Helper function to write a Command
-func writeCommand(loggers Loggers, cmd model.Command) {
+func writeCommand(loggers *Loggers, cmd model.Command) {
 	msg := fmt.Sprintf(
 		"[COMMAND] Received at %s | Action: %-12s | Params: %-8v",
-		time.Now().Local().Format("15:04:05.000000"),
+		clock.Now().Format("15:04:05.000000"),
 		cmd.Action,
 		cmd.Params,
 	)
+	if cmd.VehicleID != "" {
+		msg += fmt.Sprintf(" | Vehicle: %s", cmd.VehicleID)
+	}
+	if cmd.ScheduleID != "" {
+		msg += fmt.Sprintf(" | Scheduled: #%s", cmd.ScheduleID)
+	}
 
 	loggers.Main.Println(msg)
 	loggers.Command.Println(msg)
 }
 
+// Helper function to write an Event to the main log and the log of its type; VehicleStates, sent every second while driving, only to the latter
+func writeEvent(loggers *Loggers, e model.Event) {
+	msg := fmt.Sprintf(
+		"[%s] Created at %s, Logged at %s | Vehicle: %s",
+		strings.ToUpper(e.Type),
+		e.CreatedAt.Format("15:04:05.000000"),
+		clock.Now().Format("15:04:05.000000"),
+		e.VehicleID,
+	)
+	if e.Message != "" {
+		msg += " | " + e.Message
+	}
+	if e.Payload != nil {
+		// Leave comparison operators such as ">" in alerts readable
+		var payload strings.Builder
+		encoder := json.NewEncoder(&payload)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(e.Payload); err == nil {
+			msg += " | " + strings.TrimSuffix(payload.String(), "\n")
+		}
+	}
+
+	if e.Type != model.EventState {
+		loggers.Main.Println(msg)
+	}
+	logger, err := loggers.event(e.Type)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	logger.Println(msg)
+}
+
+/*
+Helper function to write a raw SensorFrame to the raw log only, since it would drown out the main one.
+The frame follows as JSON, with NaN readings as null, so the log can be parsed back.
+*/
+func writeFrame(loggers *Loggers, f model.SensorFrame) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		log.Println("[ERROR][Consumer][Log] Error marshalling raw frame:", err)
+		return
+	}
+	msg := fmt.Sprintf(
+		"[RAW] Vehicle: %s | Started at %s, Created at %s, Logged at %s | Samples: %d | %s",
+		f.VehicleID,
+		f.StartedAt.Format("15:04:05.000000"),
+		f.CreatedAt.Format("15:04:05.000000"),
+		clock.Now().Format("15:04:05.000000"),
+		len(f.Samples),
+		data,
+	)
+
+	logger, err := loggers.raw()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	logger.Println(msg)
+}
+
 /*
-Log receives ResultData and Command messages from their respective channels
+Log receives ResultData, Command, Event and SensorFrame messages from their respective channels
 and logs them to rotating log files.
+- Events are also written to a log of their own type (e.g. faults/, states/), created on first use;
+  "state" Events are only written to states/, so the periodic snapshots do not crowd out the other Events.
+- Raw SensorFrames are only written to raw/, created on first use.
 - Each file contains up to maxLines entries.
 - Once the limit is reached, the current file is closed and a new file is created.
 - Files are named using the creation timestamp in the format "YYYYMMDD_hhmmss".
 - If terminated early, the current file may have fewer than maxLines; a new file is created on the next run.
 */
-func Log(inResultChan <-chan model.ResultData, inCommandChan <-chan model.Command) {
+func Log(inResultChan <-chan model.ResultData, inCommandChan <-chan model.Command, inEventChan <-chan model.Event, inFrameChan <-chan model.SensorFrame) {
 	lineCount := 0
 	fileDir := config.Logger.FileDir   // defines directory where the log is saved.
 	maxLines := config.Logger.MaxLines // defines the maximum number of ResultData to log in a single file.
@@ -89,33 +285,12 @@ func Log(inResultChan <-chan model.ResultData, inCommandChan <-chan model.Comman
 	}
 
 	// Create all loggers
-	mainLogger, mainFile, err := createLogger(fileDir, "", "log")
-	if err != nil {
-		log.Println(err)
-		return
-	}
-	defer mainFile.Close()
-
-	dataLogger, dataFile, err := createLogger(fileDir, "data", "data")
-	if err != nil {
-		log.Println(err)
-		return
-	}
-	defer dataFile.Close()
-
-	commandLogger, commandFile, err := createLogger(fileDir, "commands", "command")
+	loggers, err := openLoggers(fileDir)
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	defer commandFile.Close()
-
-	// Group them for easier access
-	loggers := Loggers{
-		Main:    mainLogger,
-		Data:    dataLogger,
-		Command: commandLogger,
-	}
+	defer func() { loggers.Close() }()
 
 	log.Println("[INFO][Consumer][Log] Running.")
 
@@ -138,45 +313,42 @@ func Log(inResultChan <-chan model.ResultData, inCommandChan <-chan model.Comman
 			}
 			// Log in the file
 			writeCommand(loggers, cmd)
+
+		// Receive Event
+		case event, ok := <-inEventChan:
+			if !ok {
+				inEventChan = nil // channel closed
+				continue
+			}
+			// Log in the file
+			writeEvent(loggers, event)
+
+		// Receive SensorFrame
+		case frame, ok := <-inFrameChan:
+			if !ok {
+				inFrameChan = nil // channel closed
+				continue
+			}
+			// Log in the file
+			writeFrame(loggers, frame)
 		}
 
-		// Exit if both channels are closed
-		if inResultChan == nil && inCommandChan == nil {
+		// Exit if all channels are closed
+		if inResultChan == nil && inCommandChan == nil && inEventChan == nil && inFrameChan == nil {
 			break
 		}
 
 		lineCount++
 		if lineCount >= maxLines {
-			mainFile.Close()
-			dataFile.Close()
-			commandFile.Close()
+			loggers.Close()
 
-			// Create a new file
-			mainLogger, mainFile, err = createLogger(fileDir, "", "log")
+			// Create new files
+			loggers, err = openLoggers(fileDir)
 			if err != nil {
 				fmt.Println(err)
 				return
 			}
 
-			dataLogger, dataFile, err = createLogger(fileDir, "data", "data")
-			if err != nil {
-				fmt.Println(err)
-				return
-			}
-
-			commandLogger, commandFile, err = createLogger(fileDir, "commands", "command")
-			if err != nil {
-				fmt.Println(err)
-				return
-			}
-
-			// Group them for easier access
-			loggers = Loggers{
-				Main:    mainLogger,
-				Data:    dataLogger,
-				Command: commandLogger,
-			}
-
 			lineCount = 0
 		}
 	}
@@ -5,32 +5,47 @@ import (
 	"log"
 
 	"github.com/vasyl-ks/TM-software-H11/internal/model"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
 )
 
 /*
 Parse consumes raw JSON datagrams from the input channel,
-attempts to decode each into either a ResultData or a Command object,
-then send parsed messages to their respective output channels.
+attempts to decode each into an Event, a Command, a raw SensorFrame or a ResultData object,
+then send parsed messages to their respective output queues.
 */
-func Parse(inChan <-chan []byte, outResultChan chan<- model.ResultData, outCommandChan chan<- model.Command) {
+func Parse(inChan <-chan []byte, outResultQueue *queue.Queue[model.ResultData], outCommandQueue *queue.Queue[model.Command], outEventQueue *queue.Queue[model.Event], outFrameQueue *queue.Queue[model.SensorFrame]) {
 	log.Println("[INFO][Consumer][Parse] Running.")
 
 	for payload := range inChan {
-		// First, try to unmarshal as ResultData
-		var res model.ResultData
-		if err := json.Unmarshal(payload, &res); err == nil && res.VehicleID != "" {
-			outResultChan <- res
+		// First, try to unmarshal as Event, since its vehicleID would also satisfy ResultData
+		var event model.Event
+		if err := json.Unmarshal(payload, &event); err == nil && event.Type != "" {
+			outEventQueue.Push(event)
 			continue
 		}
 
-		// Otherwise, try to unmarshal as Command
+		// Then, try to unmarshal as Command, since a Command addressed to a vehicle would also satisfy ResultData
 		var cmd model.Command
 		if err := json.Unmarshal(payload, &cmd); err == nil && cmd.Action != "" {
-			outCommandChan <- cmd
+			outCommandQueue.Push(cmd)
+			continue
+		}
+
+		// Then, try to unmarshal as SensorFrame, since its vehicleID would also satisfy ResultData
+		var frame model.SensorFrame
+		if err := json.Unmarshal(payload, &frame); err == nil && len(frame.Samples) > 0 {
+			outFrameQueue.Push(frame)
+			continue
+		}
+
+		// Otherwise, try to unmarshal as ResultData
+		var res model.ResultData
+		if err := json.Unmarshal(payload, &res); err == nil && res.VehicleID != "" {
+			outResultQueue.Push(res)
 			continue
 		}
 
-		// If neither works, log error
+		// If none works, log error
 		log.Printf("[Error][Consumer][Parse] Unrecognized JSON payload: %s\n", string(payload))
 	}
 }
\ No newline at end of file
@@ -0,0 +1,216 @@
+package generator
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+// quantileSamples bounds the readings a channel keeps per batch for its median and percentiles.
+const quantileSamples = 512
+
+/*
+channelAggregate keeps the running statistics of one channel, updated on every reading:
+- count, mean and m2 (the sum of squared deviations from the mean) by Welford's algorithm,
+  which stays accurate without a second pass over the readings.
+- the running min and max, and the first and last reading by time.
+- a reservoir of at most quantileSamples readings for the median and percentiles, in a buffer reused across batches:
+  every reading up to quantileSamples, so the quantiles are exact, and a uniform sample of them beyond,
+  so they are estimated at a bounded memory and sorting cost whatever the size of the batch.
+NaN readings are left out.
+*/
+type channelAggregate struct {
+	count           int
+	mean, m2        float64
+	min, max        float32
+	first, last     float32
+	firstAt, lastAt time.Time
+	values          []float64 // the reservoir
+	state           uint64    // of the generator drawing the reservoir, restarted every batch
+}
+
+// add takes the reading v of the channel, taken at at.
+func (c *channelAggregate) add(v float32, at time.Time) {
+	if !isValid(v) {
+		return
+	}
+	x := float64(v)
+	c.count++
+	delta := x - c.mean
+	c.mean += delta / float64(c.count)
+	c.m2 += delta * (x - c.mean)
+
+	if c.count == 1 {
+		c.min, c.max = v, v
+		c.first, c.firstAt = v, at
+		c.last, c.lastAt = v, at
+	} else {
+		if v < c.min {
+			c.min = v
+		}
+		if v > c.max {
+			c.max = v
+		}
+		if at.Before(c.firstAt) {
+			c.first, c.firstAt = v, at
+		}
+		if !at.Before(c.lastAt) {
+			c.last, c.lastAt = v, at
+		}
+	}
+
+	// Reservoir sampling: the n-th reading replaces a kept one with probability quantileSamples/n
+	if len(c.values) < quantileSamples {
+		c.values = append(c.values, x)
+	} else if i := c.random() % uint64(c.count); i < quantileSamples {
+		c.values[i] = x
+	}
+}
+
+// random returns the next number of a xorshift generator, deterministic so that equal batches summarize equally.
+func (c *channelAggregate) random() uint64 {
+	if c.state == 0 {
+		c.state = 0x9e3779b97f4a7c15
+	}
+	c.state ^= c.state >> 12
+	c.state ^= c.state << 25
+	c.state ^= c.state >> 27
+	return c.state * 0x2545f4914f6cdd1d
+}
+
+// reset forgets the readings, keeping the buffer for the next batch.
+func (c *channelAggregate) reset() {
+	*c = channelAggregate{values: c.values[:0]}
+}
+
+/*
+stats returns the distribution of the readings: their count, sample standard deviation,
+median and percentiles, the first and last reading by time and the rate of change between them.
+It sorts the reservoir in place.
+*/
+func (c *channelAggregate) stats(percentiles []float64) model.ChannelStats {
+	stats := model.ChannelStats{Count: c.count}
+	if c.count == 0 {
+		return stats
+	}
+
+	stats.First, stats.Last = c.first, c.last
+	if span := c.lastAt.Sub(c.firstAt).Seconds(); span > 0 {
+		stats.Rate = float32((float64(c.last) - float64(c.first)) / span)
+	}
+	if c.count > 1 {
+		stats.StdDev = float32(math.Sqrt(c.m2 / float64(c.count-1)))
+	}
+
+	sort.Float64s(c.values)
+	stats.Median = float32(percentile(c.values, 50))
+	if len(percentiles) > 0 {
+		stats.Percentiles = make(map[string]float32, len(percentiles))
+		for _, p := range percentiles {
+			stats.Percentiles[percentileName(p)] = float32(percentile(c.values, p))
+		}
+	}
+	return stats
+}
+
+/*
+aggregator summarizes a batch of SensorData incrementally: every reading updates the running statistics
+of each channel as it arrives, so closing the batch is a single pass over the channels, without goroutines.
+The zero value is an empty batch, and reset empties it again while keeping its buffers.
+*/
+type aggregator struct {
+	channels   []channelAggregate // indexed like channelReadings
+	count      int
+	outOfRange int
+	vehicleID  string           // of the first reading
+	latest     model.SensorData // by CreatedAt
+	tripID     string           // of the last reading taken in a trip
+}
+
+// add takes the next reading of the batch, checked against the limits of sensor.
+func (a *aggregator) add(d model.SensorData, sensor config.SensorConfig) {
+	if a.channels == nil {
+		a.channels = make([]channelAggregate, len(channelReadings))
+	}
+	for i, get := range channelReadings {
+		a.channels[i].add(get(d), d.CreatedAt)
+	}
+
+	if a.count == 0 {
+		a.vehicleID = d.VehicleID
+	}
+	if a.count == 0 || d.CreatedAt.After(a.latest.CreatedAt) {
+		a.latest = d
+	}
+	if d.TripID != "" {
+		a.tripID = d.TripID
+	}
+	if outOfRange(d, sensor) {
+		a.outOfRange++
+	}
+	a.count++
+}
+
+// reset empties the batch.
+func (a *aggregator) reset() {
+	for i := range a.channels {
+		a.channels[i].reset()
+	}
+	a.count, a.outOfRange, a.vehicleID, a.latest, a.tripID = 0, 0, "", model.SensorData{}, ""
+}
+
+/*
+result returns the statistics of the batch closed at processedAt, reporting the given percentiles,
+and assessed against the readings expected in it.
+An empty batch has no statistics: it returns a Stale result, which the caller stamps with its vehicle.
+*/
+func (a *aggregator) result(processedAt time.Time, percentiles []float64, expected float32) model.ResultData {
+	if a.count == 0 {
+		return model.ResultData{Stale: true, ExpectedCount: expected, ProcessedAt: processedAt}
+	}
+
+	ch := a.channels
+	stats := make(map[string]model.ChannelStats, len(ch))
+	for i := range ch {
+		stats[sensorChannels[i]] = ch[i].stats(percentiles)
+	}
+
+	speed, pressure, temperature := &ch[0], &ch[1], &ch[2]
+	voltage, current, soc, batteryTemperature := &ch[3], &ch[4], &ch[5], &ch[6]
+	return model.ResultData{
+		AverageSpeed:              float32(speed.mean),
+		MinimumSpeed:              speed.min,
+		MaximumSpeed:              speed.max,
+		AverageTemperature:        float32(temperature.mean),
+		MinimumTemperature:        temperature.min,
+		MaximumTemperature:        temperature.max,
+		AveragePressure:           float32(pressure.mean),
+		MinimumPressure:           pressure.min,
+		MaximumPressure:           pressure.max,
+		AverageBatteryVoltage:     float32(voltage.mean),
+		MinimumBatteryVoltage:     voltage.min,
+		MaximumBatteryVoltage:     voltage.max,
+		AverageBatteryCurrent:     float32(current.mean),
+		MinimumBatteryCurrent:     current.min,
+		MaximumBatteryCurrent:     current.max,
+		AverageBatterySoC:         float32(soc.mean),
+		MinimumBatterySoC:         soc.min,
+		MaximumBatterySoC:         soc.max,
+		AverageBatteryTemperature: float32(batteryTemperature.mean),
+		MinimumBatteryTemperature: batteryTemperature.min,
+		MaximumBatteryTemperature: batteryTemperature.max,
+		Position:                  a.latest.Position,
+		DistanceToEnd:             a.latest.DistanceToEnd,
+		Count:                     a.count,
+		ExpectedCount:             expected,
+		OutOfRangeCount:           a.outOfRange,
+		Stats:                     stats,
+		TripID:                    a.tripID,
+		VehicleID:                 a.vehicleID,
+		CreatedAt:                 a.latest.CreatedAt,
+		ProcessedAt:               processedAt,
+	}
+}
@@ -0,0 +1,267 @@
+package generator
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+/*
+alertRule is an AlertRule bound to the value it reads, with the state of its alarm:
+- inactive: once the condition holds, it is pending since the time of that value.
+- pending: once it held for For, the alarm is raised; a value that breaks it first starts over.
+- raised: once the value is back past the threshold by Hysteresis, the alarm is cleared.
+Values that are missing (a stale batch, a NaN reading or an unreported percentile) leave the state as is.
+*/
+type alertRule struct {
+	config.AlertRule
+	result  func(model.ResultData) (float64, bool) // batch and window rules
+	sample  func(model.SensorData) (float64, bool) // sample rules
+	pending bool
+	raised  bool
+	since   time.Time // when the condition started holding
+}
+
+// holds reports whether value compares to threshold by the op of the rule.
+func (r *alertRule) holds(value, threshold float64) bool {
+	switch r.Op {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	default:
+		return value <= threshold
+	}
+}
+
+// clearThreshold returns the threshold a raised alarm has to move back past to clear.
+func (r *alertRule) clearThreshold() float64 {
+	if r.Op == ">" || r.Op == ">=" {
+		return r.Threshold - r.Hysteresis
+	}
+	return r.Threshold + r.Hysteresis
+}
+
+// observe updates the rule with value, taken at at, and returns the Alert it raised or cleared, if any.
+func (r *alertRule) observe(value float64, at time.Time) (model.Alert, bool) {
+	if r.raised {
+		if r.holds(value, r.clearThreshold()) {
+			return model.Alert{}, false
+		}
+		r.raised, r.pending = false, false
+		return r.alert(model.AlertCleared, value), true
+	}
+
+	if !r.holds(value, r.Threshold) {
+		r.pending = false
+		return model.Alert{}, false
+	}
+	if !r.pending {
+		r.pending, r.since = true, at
+	}
+	if at.Sub(r.since) < r.For {
+		return model.Alert{}, false
+	}
+	r.raised = true
+	return r.alert(model.AlertRaised, value), true
+}
+
+func (r *alertRule) alert(state string, value float64) model.Alert {
+	return model.Alert{
+		Rule:      r.Name,
+		Severity:  r.Severity,
+		State:     state,
+		Source:    r.Source,
+		Field:     r.Field,
+		Op:        r.Op,
+		Threshold: r.Threshold,
+		Value:     value,
+		Since:     r.since,
+	}
+}
+
+/*
+alerting evaluates the alert rules of a vehicle (config.Alerts) on its readings and results,
+and hands an "alert" Event to emit whenever an alarm is raised or cleared.
+Rules are evaluated on the time of the data (CreatedAt of readings, the end of batches and windows),
+so replays and seeded runs raise the same alarms.
+*/
+type alerting struct {
+	vehicleID string
+	rules     []*alertRule
+	emit      func(model.Event)
+}
+
+// newAlerting binds rules to the values they read. Rules whose field their source does not report are left out.
+func newAlerting(vehicleID string, rules []config.AlertRule, emit func(model.Event)) *alerting {
+	a := &alerting{vehicleID: vehicleID, emit: emit}
+	for _, cfg := range rules {
+		r := &alertRule{AlertRule: cfg}
+		var err error
+		if cfg.Source == config.AlertSourceSample {
+			r.sample, err = sampleField(cfg.Field)
+		} else {
+			r.result, err = resultField(cfg.Field)
+		}
+		if err != nil {
+			log.Printf("[ERROR][Generator][Alerts] %s rule %q: %v, ignoring it.", vehicleID, cfg.Name, err)
+			continue
+		}
+		a.rules = append(a.rules, r)
+	}
+	return a
+}
+
+// sample evaluates the sample rules on a reading.
+func (a *alerting) sample(data model.SensorData) {
+	for _, r := range a.rules {
+		if r.sample == nil {
+			continue
+		}
+		if value, ok := r.sample(data); ok {
+			a.observe(r, value, data.CreatedAt)
+		}
+	}
+}
+
+// result evaluates the rules of its source on a batch or window ResultData. Stale results have no values.
+func (a *alerting) result(res model.ResultData) {
+	if res.Stale {
+		return
+	}
+	source := res.Window
+	if source == "" {
+		source = config.AlertSourceBatch
+	}
+	for _, r := range a.rules {
+		if r.result == nil || r.Source != source {
+			continue
+		}
+		if value, ok := r.result(res); ok {
+			a.observe(r, value, res.ProcessedAt)
+		}
+	}
+}
+
+func (a *alerting) observe(r *alertRule, value float64, at time.Time) {
+	alert, ok := r.observe(value, at)
+	if !ok {
+		return
+	}
+
+	var message string
+	if alert.State == model.AlertRaised {
+		message = fmt.Sprintf("Raised %s alert %s: %s %.2f %s %g", alert.Severity, alert.Rule, alert.Field, value, alert.Op, alert.Threshold)
+		if r.For > 0 {
+			message += fmt.Sprintf(" for %s", r.For)
+		}
+		message += "."
+		log.Printf("[WARN][Generator][Alerts] %s %s", a.vehicleID, message)
+	} else {
+		message = fmt.Sprintf("Cleared %s alert %s: %s %.2f.", alert.Severity, alert.Rule, alert.Field, value)
+		log.Printf("[INFO][Generator][Alerts] %s %s", a.vehicleID, message)
+	}
+
+	if a.emit != nil {
+		a.emit(model.Event{Type: model.EventAlert, VehicleID: a.vehicleID, Message: message, Payload: alert, CreatedAt: at})
+	}
+}
+
+// sampleField returns the reading of the sensor channel named field, matched case-insensitively.
+func sampleField(field string) (func(model.SensorData) (float64, bool), error) {
+	channel, ok := sensorChannel(field)
+	if !ok {
+		return nil, fmt.Errorf("unknown channel %q", field)
+	}
+	get := channelReadings[channelIndex(channel)]
+	return func(d model.SensorData) (float64, bool) {
+		v := get(d)
+		return float64(v), isValid(v)
+	}, nil
+}
+
+/*
+resultField returns the value of field in a ResultData, matched case-insensitively:
+- a field of the data log, e.g. "AverageTemperature", "MaxPressure" or "Position".
+- "Count" or "OutOfRangeCount", the data-quality counts.
+- "<channel>.<statistic>", a statistic of Stats, e.g. "temperature.stddev", "speed.rate" or "pressure.p99"
+  (count, stddev, median, first, last, rate or a configured percentile).
+*/
+func resultField(field string) (func(model.ResultData) (float64, bool), error) {
+	name := strings.ToLower(field)
+	switch name {
+	case "count":
+		return func(r model.ResultData) (float64, bool) { return float64(r.Count), true }, nil
+	case "outofrangecount":
+		return func(r model.ResultData) (float64, bool) { return float64(r.OutOfRangeCount), true }, nil
+	}
+	// The field map is built once, on a scratch ResultData each evaluated result is copied into,
+	// so evaluating a rule does not rebuild it. Every alerting compiles its own rules, in its own goroutine.
+	var scratch model.ResultData
+	if field, ok := resultFields(&scratch)[name]; ok {
+		return func(r model.ResultData) (float64, bool) {
+			scratch = r
+			return float64(*field), true
+		}, nil
+	}
+
+	channelName, stat, ok := strings.Cut(field, ".")
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", field)
+	}
+	channel, ok := sensorChannel(channelName)
+	if !ok {
+		return nil, fmt.Errorf("unknown channel %q", channelName)
+	}
+
+	var get func(model.ChannelStats) (float64, bool)
+	switch stat = strings.ToLower(stat); stat {
+	case "count":
+		get = func(s model.ChannelStats) (float64, bool) { return float64(s.Count), true }
+	case "stddev":
+		get = func(s model.ChannelStats) (float64, bool) { return float64(s.StdDev), true }
+	case "median":
+		get = func(s model.ChannelStats) (float64, bool) { return float64(s.Median), true }
+	case "first":
+		get = func(s model.ChannelStats) (float64, bool) { return float64(s.First), true }
+	case "last":
+		get = func(s model.ChannelStats) (float64, bool) { return float64(s.Last), true }
+	case "rate":
+		get = func(s model.ChannelStats) (float64, bool) { return float64(s.Rate), true }
+	default:
+		p, err := strconv.ParseFloat(strings.TrimPrefix(stat, "p"), 64)
+		if !strings.HasPrefix(stat, "p") || err != nil {
+			return nil, fmt.Errorf("unknown statistic %q", stat)
+		}
+		key := percentileName(p)
+		get = func(s model.ChannelStats) (float64, bool) {
+			v, ok := s.Percentiles[key]
+			return float64(v), ok
+		}
+	}
+
+	return func(r model.ResultData) (float64, bool) {
+		s, ok := r.Stats[channel]
+		if !ok || s.Count == 0 {
+			return 0, false
+		}
+		return get(s)
+	}, nil
+}
+
+// channelIndex returns the index of a channel of sensorChannels, or -1.
+func channelIndex(channel string) int {
+	for i, c := range sensorChannels {
+		if c == channel {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+func TestAlertsHoldAndHysteresis(t *testing.T) {
+	rules := []config.AlertRule{
+		{Name: "hot", Field: "AverageTemperature", Op: ">", Threshold: 45, Hysteresis: 2, For: 200 * time.Millisecond, Severity: config.SeverityCritical, Source: config.AlertSourceBatch},
+		{Name: "jittery", Field: "pressure.p99", Op: ">=", Threshold: 3, Severity: config.SeverityWarning, Source: "smooth"},
+		{Name: "slow", Field: "speed", Op: "<", Threshold: 5, Severity: config.SeverityInfo, Source: config.AlertSourceSample},
+		{Name: "bogus", Field: "speed.variance", Op: ">", Threshold: 1, Source: config.AlertSourceBatch},
+	}
+	var events []model.Event
+	a := newAlerting("123", rules, func(e model.Event) { events = append(events, e) })
+	if len(a.rules) != 3 {
+		t.Fatalf("%d rules bound, want 3 (the unknown statistic left out)", len(a.rules))
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	batch := func(i int, temp float32) model.ResultData {
+		return model.ResultData{AverageTemperature: temp, Count: 10, ProcessedAt: start.Add(time.Duration(i) * 100 * time.Millisecond)}
+	}
+	states := func() []string {
+		var s []string
+		for _, e := range events {
+			alert := e.Payload.(model.Alert)
+			s = append(s, alert.Rule+" "+alert.State)
+		}
+		events = nil
+		return s
+	}
+
+	// Held for 100ms, broken, then held for 200ms: raised on the third batch above 45
+	for i, temp := range []float32{46, 46, 44, 46, 47, 48} {
+		a.result(batch(i, temp))
+		if i == 4 && len(events) != 0 {
+			t.Error("raised after 100ms above the threshold, want 200ms")
+		}
+	}
+	if got := states(); len(got) != 1 || got[0] != "hot raised" {
+		t.Fatalf("events = %v, want hot raised", got)
+	}
+
+	// Within the hysteresis band it stays raised; a stale batch is ignored
+	a.result(batch(6, 44))
+	a.result(model.ResultData{Stale: true, ProcessedAt: start.Add(700 * time.Millisecond)})
+	if got := states(); len(got) != 0 {
+		t.Errorf("events within the hysteresis band = %v, want none", got)
+	}
+	a.result(batch(8, 42.9))
+	if got := states(); len(got) != 1 || got[0] != "hot cleared" {
+		t.Errorf("events = %v, want hot cleared", got)
+	}
+
+	// Window rules only see the results of their window; a missing percentile is ignored
+	stats := map[string]model.ChannelStats{"pressure": {Count: 10, Percentiles: map[string]float32{"p99": 3.1}}}
+	a.result(model.ResultData{Stats: stats, ProcessedAt: start})
+	a.result(model.ResultData{Window: "smooth", Stats: map[string]model.ChannelStats{"pressure": {Count: 10}}, ProcessedAt: start})
+	if got := states(); len(got) != 0 {
+		t.Errorf("events = %v, want none", got)
+	}
+	a.result(model.ResultData{Window: "smooth", Stats: stats, ProcessedAt: start})
+	if got := states(); len(got) != 1 || got[0] != "jittery raised" {
+		t.Errorf("events = %v, want jittery raised", got)
+	}
+
+	// Sample rules see every reading, skipping NaN
+	for _, speed := range []float32{10, 4, float32(math.NaN()), 6} {
+		a.sample(model.SensorData{Speed: speed, CreatedAt: start})
+	}
+	if got := states(); len(got) != 2 || got[0] != "slow raised" || got[1] != "slow cleared" {
+		t.Errorf("events = %v, want slow raised and cleared", got)
+	}
+}
@@ -0,0 +1,257 @@
+package generator
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
+)
+
+// ewma keeps the exponentially weighted mean and variance of a series.
+type ewma struct {
+	alpha          float64
+	n              int
+	mean, variance float64
+}
+
+func (e *ewma) add(x float64) {
+	if e.n == 0 {
+		e.mean = x
+	} else {
+		diff := x - e.mean
+		incr := e.alpha * diff
+		e.mean += incr
+		e.variance = (1 - e.alpha) * (e.variance + diff*incr)
+	}
+	e.n++
+}
+
+// zScore returns how many standard deviations, at least floor, x is from the mean.
+func (e *ewma) zScore(x, floor float64) (z, stdDev float64) {
+	stdDev = math.Max(math.Sqrt(e.variance), floor)
+	return (x - e.mean) / stdDev, stdDev
+}
+
+/*
+channelBaseline follows the batches of one channel of a vehicle:
+- level: the EWMA of the batch averages, for the z-score check.
+- spread: the EWMA of the standard deviation within the batches, for the spread check.
+- rate: the EWMA of the change per second of the averages between batches, for the rate-of-change check.
+A check reports a channel when it turns anomalous and stays quiet until the channel is back to normal.
+The baselines keep learning from anomalous values, so a lasting shift is reported once and becomes the new normal.
+*/
+type channelBaseline struct {
+	level, spread, rate             ewma
+	last                            float64
+	lastAt                          time.Time
+	levelFlag, spreadFlag, rateFlag bool // whether each check found the channel anomalous at the last batch
+}
+
+// turned records whether a check finds the channel anomalous and reports whether it just turned so.
+func turned(flag *bool, anomalous bool) bool {
+	was := *flag
+	*flag = anomalous
+	return anomalous && !was
+}
+
+/*
+check adds the batch ending at at, with average x and standard deviation spread (NaN when unknown),
+and returns the anomalies it turned into:
+- zscore: x is more than zThreshold standard deviations from its baseline, once it saw warmup batches.
+- spread: so is spread, e.g. on spikes.
+- rate: the change per second since the last batch is above limit, when set, or zThreshold standard deviations
+  from its baseline.
+floor is the lowest standard deviation of the level and the spread; the rate floor is a change of floor within the batch.
+*/
+func (c *channelBaseline) check(x, spread float64, at time.Time, cfg config.AnomalyConfig, limit, floor float64) []model.Anomaly {
+	var found []model.Anomaly
+	checked := cfg.ZThreshold > 0 && c.level.n >= cfg.Warmup
+
+	if checked {
+		z, stdDev := c.level.zScore(x, floor)
+		if turned(&c.levelFlag, math.Abs(z) > cfg.ZThreshold) {
+			found = append(found, model.Anomaly{Check: model.AnomalyZScore, Value: x, Baseline: c.level.mean, StdDev: stdDev, ZScore: z})
+		}
+	}
+
+	if !math.IsNaN(spread) {
+		if checked && c.spread.n >= cfg.Warmup {
+			z, stdDev := c.spread.zScore(spread, floor)
+			if turned(&c.spreadFlag, math.Abs(z) > cfg.ZThreshold) {
+				found = append(found, model.Anomaly{Check: model.AnomalySpread, Value: spread, Baseline: c.spread.mean, StdDev: stdDev, ZScore: z})
+			}
+		}
+		c.spread.add(spread)
+	}
+
+	if c.level.n > 0 && at.After(c.lastAt) {
+		dt := at.Sub(c.lastAt).Seconds()
+		rate := (x - c.last) / dt
+		z, stdDev := c.rate.zScore(rate, floor/dt)
+		exceeded := limit > 0 && math.Abs(rate) > limit
+		if turned(&c.rateFlag, exceeded || (checked && c.rate.n >= cfg.Warmup && math.Abs(z) > cfg.ZThreshold)) {
+			a := model.Anomaly{Check: model.AnomalyRate, Value: rate, Baseline: c.rate.mean, StdDev: stdDev, ZScore: z}
+			if exceeded {
+				a.Limit = limit
+			}
+			found = append(found, a)
+		}
+		c.rate.add(rate)
+	}
+
+	c.level.add(x)
+	c.last, c.lastAt = x, at
+	return found
+}
+
+/*
+detector checks the batches of every vehicle for anomalies, keeping a channelBaseline
+per vehicle and checked channel, and hands an "anomaly" Event to emit for each one.
+The standard deviations of the baselines are floored at a fraction of the channel range (StdDevFloor),
+so a channel that barely moves, like a noiseless one, is not flagged for a quantization step.
+*/
+type detector struct {
+	cfg       config.AnomalyConfig
+	channels  []string                                 // checked, of sensorChannels
+	averages  []func(model.ResultData) (float64, bool) // indexed like channels
+	baselines map[string][]channelBaseline             // by vehicle, indexed like channels
+	floors    map[string][]float64                     // by vehicle, indexed like channels
+	emit      func(model.Event)
+}
+
+/*
+newDetector builds the detector of cfg, resolving the average field of every checked channel once.
+Unknown channels, and those without an average, are left out.
+*/
+func newDetector(cfg config.AnomalyConfig, emit func(model.Event)) *detector {
+	d := &detector{cfg: cfg, baselines: make(map[string][]channelBaseline), floors: make(map[string][]float64), emit: emit}
+	names := cfg.Channels
+	if len(names) == 0 {
+		names = sensorChannels
+	}
+	for _, name := range names {
+		channel, ok := sensorChannel(name)
+		if !ok {
+			log.Printf("[ERROR][Generator][Detect] Unknown anomaly channel %q, ignoring it.", name)
+			continue
+		}
+		average, err := resultField("average" + channel)
+		if err != nil {
+			log.Printf("[ERROR][Generator][Detect] Anomaly channel %s has no average, ignoring it.", channel)
+			continue
+		}
+		d.channels = append(d.channels, channel)
+		d.averages = append(d.averages, average)
+	}
+	for name := range cfg.MaxRate {
+		if _, ok := sensorChannel(name); !ok {
+			log.Printf("[ERROR][Generator][Detect] Unknown maxRate channel %q, ignoring it.", name)
+		}
+	}
+	return d
+}
+
+// enabled reports whether any check is configured.
+func (d *detector) enabled() bool {
+	return d.cfg.ZThreshold > 0 || len(d.cfg.MaxRate) > 0
+}
+
+// maxRate returns the configured rate limit of channel, matched case-insensitively, or 0.
+func (d *detector) maxRate(channel string) float64 {
+	for name, limit := range d.cfg.MaxRate {
+		if strings.EqualFold(name, channel) {
+			return limit
+		}
+	}
+	return 0
+}
+
+// vehicle returns the baselines and floors of vehicleID, creating them on its first batch.
+func (d *detector) vehicle(vehicleID string) ([]channelBaseline, []float64) {
+	if baselines, ok := d.baselines[vehicleID]; ok {
+		return baselines, d.floors[vehicleID]
+	}
+
+	sensor := config.Sensor
+	for _, v := range config.Vehicles {
+		if v.VehicleID == vehicleID {
+			sensor = v.Sensor
+		}
+	}
+	baselines := make([]channelBaseline, len(d.channels))
+	floors := make([]float64, len(d.channels))
+	for i, channel := range d.channels {
+		baselines[i].level.alpha, baselines[i].spread.alpha, baselines[i].rate.alpha = d.cfg.Alpha, d.cfg.Alpha, d.cfg.Alpha
+		floors[i] = d.cfg.StdDevFloor * float64(channelRange(sensor, channel))
+	}
+	d.baselines[vehicleID], d.floors[vehicleID] = baselines, floors
+	return baselines, floors
+}
+
+// check checks the channels of a batch ResultData. Stale results and windows are skipped.
+func (d *detector) check(result model.ResultData) {
+	if !d.enabled() || result.Stale || result.Window != "" {
+		return
+	}
+
+	baselines, floors := d.vehicle(result.VehicleID)
+	for i, channel := range d.channels {
+		// A channel without valid readings has no average; results recorded without Stats have no spread
+		spread := math.NaN()
+		if stats, ok := result.Stats[channel]; ok {
+			if stats.Count == 0 {
+				continue
+			}
+			if stats.Count > 1 {
+				spread = float64(stats.StdDev)
+			}
+		}
+		x, _ := d.averages[i](result)
+		for _, a := range baselines[i].check(x, spread, result.ProcessedAt, d.cfg, d.maxRate(channel), floors[i]) {
+			a.Channel = channel
+			d.report(result.VehicleID, a, result.ProcessedAt)
+		}
+	}
+}
+
+func (d *detector) report(vehicleID string, a model.Anomaly, at time.Time) {
+	var message string
+	switch {
+	case a.Limit > 0:
+		message = fmt.Sprintf("Anomalous %s rate: %.2f/s, above the limit of %g/s.", a.Channel, a.Value, a.Limit)
+	case a.Check == model.AnomalyRate:
+		message = fmt.Sprintf("Anomalous %s rate: %.2f/s against a baseline of %.2f/s (z %.1f).", a.Channel, a.Value, a.Baseline, a.ZScore)
+	case a.Check == model.AnomalySpread:
+		message = fmt.Sprintf("Anomalous %s spread: %.2f against a baseline of %.2f ± %.2f (z %.1f).", a.Channel, a.Value, a.Baseline, a.StdDev, a.ZScore)
+	default:
+		message = fmt.Sprintf("Anomalous %s: %.2f against a baseline of %.2f ± %.2f (z %.1f).", a.Channel, a.Value, a.Baseline, a.StdDev, a.ZScore)
+	}
+	log.Printf("[WARN][Generator][Detect] %s %s", vehicleID, message)
+
+	if d.emit != nil {
+		d.emit(model.Event{Type: model.EventAnomaly, VehicleID: vehicleID, Message: message, Payload: a, CreatedAt: at})
+	}
+}
+
+/*
+Detect is the stage after Process: it receives the ResultData of every vehicle from inChan,
+forwards each one unchanged to outQueue and checks its channels for anomalies (see detector),
+pushing an "anomaly" Event to outEventQueue for every channel that turns anomalous.
+Detection runs on the time of the batches, so seeded runs and replays report the same anomalies.
+*/
+func Detect(inChan <-chan model.ResultData, outQueue *queue.Queue[model.ResultData], outEventQueue *queue.Queue[model.Event]) {
+	d := newDetector(config.Anomaly, func(e model.Event) { outEventQueue.Push(e) })
+	if d.enabled() {
+		log.Printf("[INFO][Generator][Detect] Checking %d channels (z > %g, alpha %g, warmup %d).", len(d.channels), d.cfg.ZThreshold, d.cfg.Alpha, d.cfg.Warmup)
+	}
+
+	for result := range inChan {
+		outQueue.Push(result)
+		d.check(result)
+	}
+}
@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+func TestDetectorFlagsAnomaliesOnce(t *testing.T) {
+	cfg := config.AnomalyConfig{
+		ZThreshold: 6, Alpha: 0.1, Warmup: 20, StdDevFloor: 0.001,
+		Channels: []string{"temperature"}, MaxRate: map[string]float64{"temperature": 20},
+	}
+	var found []model.Anomaly
+	d := newDetector(cfg, func(e model.Event) { found = append(found, e.Payload.(model.Anomaly)) })
+
+	// goldenVehicle is not configured, so the default sensor sets the floors
+	defer func(sensor config.SensorConfig) { config.Sensor = sensor }(config.Sensor)
+	config.Sensor = goldenVehicle.Sensor
+	rng := rand.New(rand.NewSource(1))
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	batch := func(i int, temp, stdDev float64) model.ResultData {
+		return model.ResultData{
+			VehicleID:          "golden",
+			AverageTemperature: float32(temp + 0.05*rng.NormFloat64()),
+			Stats:              map[string]model.ChannelStats{"temperature": {Count: 10, StdDev: float32(stdDev)}},
+			ProcessedAt:        start.Add(time.Duration(i) * 100 * time.Millisecond),
+		}
+	}
+	checks := func() []string {
+		var c []string
+		for _, a := range found {
+			c = append(c, a.Check)
+		}
+		found = nil
+		return c
+	}
+
+	// Noise around 30°C, with a jump during warmup that is not checked yet
+	for i := 0; i < 40; i++ {
+		temp := 30.0
+		if i == 5 {
+			temp = 35
+		}
+		d.check(batch(i, temp, 0.15))
+	}
+	if got := checks(); len(got) != 1 || got[0] != model.AnomalyRate {
+		t.Fatalf("anomalies while warming up and on noise = %v, want the rate above maxRate only", got)
+	}
+
+	// A step to 33°C is reported once, even though the baseline takes a few batches to follow it
+	for i := 40; i < 45; i++ {
+		d.check(batch(i, 33, 0.15))
+	}
+	if got := checks(); len(got) != 2 || got[0] != model.AnomalyZScore || got[1] != model.AnomalyRate {
+		t.Errorf("anomalies on a step = %v, want zscore and rate once", got)
+	}
+
+	// Spikes widen the batch without moving its average much
+	for i := 45; i < 100; i++ {
+		d.check(batch(i, 33, 0.15))
+	}
+	checks()
+	d.check(batch(100, 33, 3))
+	if got := checks(); len(got) != 1 || got[0] != model.AnomalySpread {
+		t.Errorf("anomalies on spikes = %v, want spread", got)
+	}
+
+	// Windows and stale batches are not checked
+	stale := batch(101, 50, 3)
+	stale.Stale = true
+	window := batch(101, 50, 3)
+	window.Window = "smooth"
+	d.check(stale)
+	d.check(window)
+	if got := checks(); len(got) != 0 {
+		t.Errorf("anomalies on stale and window results = %v, want none", got)
+	}
+}
+
+func TestDetectorChannels(t *testing.T) {
+	d := newDetector(config.AnomalyConfig{Channels: []string{"Temperature", "altitude", "batterySoC"}}, nil)
+	if len(d.channels) != 2 || d.channels[0] != "temperature" || d.channels[1] != "batterySoC" || len(d.averages) != len(d.channels) {
+		t.Fatalf("channels %v with %d averages, want [temperature batterySoC] with 2", d.channels, len(d.averages))
+	}
+	if x, ok := d.averages[1](model.ResultData{AverageBatterySoC: 81.5}); !ok || x != 81.5 {
+		t.Errorf("batterySoC average = %v, want 81.5", x)
+	}
+
+	// Every sensor channel has an average, so none is left out by default
+	if d := newDetector(config.AnomalyConfig{}, nil); len(d.channels) != len(sensorChannels) || len(d.averages) != len(sensorChannels) {
+		t.Errorf("default detector checks %v with %d averages, want every sensor channel", d.channels, len(d.averages))
+	}
+}
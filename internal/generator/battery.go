@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"math"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+)
+
+/*
+battery simulates the traction battery pack.
+Each step it supplies the traction power plus the auxiliary draw:
+- open-circuit voltage grows linearly with the state of charge between minVoltage and maxVoltage.
+- current solves P = (OCV - I·R)·I, so voltage sags under load as V = OCV - I·R,
+  and never exceeds the rated maxCurrent: past it the pack supplies less than demanded.
+- state of charge drops by I·dt over the capacity.
+- cell temperature rises with the I²·R losses and cools toward ambient.
+*/
+type battery struct {
+	cfg         config.Battery
+	soc         float64 // 0..1
+	voltage     float64 // V
+	current     float64 // A
+	temperature float64 // °C
+}
+
+func newBattery(cfg config.Battery) *battery {
+	b := &battery{
+		cfg:         cfg,
+		soc:         cfg.InitialSoC,
+		temperature: cfg.AmbientTemp,
+	}
+	b.voltage = b.openCircuitVoltage()
+	return b
+}
+
+// openCircuitVoltage returns the unloaded pack voltage at the current state of charge.
+func (b *battery) openCircuitVoltage() float64 {
+	return b.cfg.MinVoltage + b.soc*(b.cfg.MaxVoltage-b.cfg.MinVoltage)
+}
+
+// empty reports whether the pack can no longer supply traction.
+func (b *battery) empty() bool {
+	return b.soc <= 0
+}
+
+// step draws mechanicalPower (W) from the pack for dt seconds.
+func (b *battery) step(mechanicalPower, dt float64) {
+	ocv := b.openCircuitVoltage()
+	r := b.cfg.InternalResistance
+
+	// Electrical power demanded by the drivetrain and the electronics
+	efficiency := b.cfg.DrivetrainEfficiency
+	if efficiency <= 0 {
+		efficiency = 1
+	}
+	power := math.Max(0, mechanicalPower)/efficiency + b.cfg.AuxiliaryCurrent*ocv
+
+	// Solve P = (OCV - I·R)·I for the smallest current, capped at the maximum transferable power
+	switch {
+	case b.empty() || ocv <= 0:
+		b.current = 0
+	case r <= 0:
+		b.current = power / ocv
+	default:
+		discriminant := ocv*ocv - 4*r*power
+		if discriminant < 0 {
+			discriminant = 0
+		}
+		b.current = (ocv - math.Sqrt(discriminant)) / (2 * r)
+	}
+	if b.cfg.MaxCurrent > 0 {
+		b.current = math.Min(b.current, b.cfg.MaxCurrent)
+	}
+	b.voltage = ocv - b.current*r
+
+	// Coulomb counting
+	if b.cfg.Capacity > 0 {
+		b.soc -= b.current * dt / 3600 / b.cfg.Capacity
+		if b.soc < 0 {
+			b.soc = 0
+		}
+	}
+
+	// Joule heating and cooling toward ambient
+	if b.cfg.HeatCapacity > 0 {
+		heat := b.current*b.current*r - b.cfg.CoolingCoefficient*(b.temperature-b.cfg.AmbientTemp)
+		b.temperature += heat / b.cfg.HeatCapacity * dt
+	}
+}
@@ -0,0 +1,67 @@
+package generator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBatteryModel(t *testing.T) {
+	cfg := goldenVehicle.Sensor.Battery
+	cfg.AuxiliaryCurrent = 0
+	cfg.DrivetrainEfficiency = 1
+
+	// At rest the pack reads its open-circuit voltage and stays at ambient
+	b := newBattery(cfg)
+	b.step(0, 60)
+	if ocv := 300 + 0.95*100; b.current != 0 || b.voltage != ocv || b.soc != 0.95 || b.temperature != cfg.AmbientTemp {
+		t.Fatalf("at rest: %g A, %g V, SoC %g, %g °C, want 0 A at %g V, SoC 0.95, %g °C", b.current, b.voltage, b.soc, b.temperature, ocv, cfg.AmbientTemp)
+	}
+
+	// Under load the voltage sags by I·R and the pack delivers the power demanded
+	ocv := b.openCircuitVoltage()
+	b.step(20000, 0.01)
+	if math.Abs(b.voltage-(ocv-b.current*cfg.InternalResistance)) > 1e-9 || b.voltage >= ocv {
+		t.Errorf("under 20 kW: %g V at %g A, want a sag of I·R below %g V", b.voltage, b.current, ocv)
+	}
+	if p := b.voltage * b.current; math.Abs(p-20000) > 1e-6 {
+		t.Errorf("delivered %g W, want 20000", p)
+	}
+
+	// Coulomb counting: 10 minutes at 50 A and more, as the voltage drops, drain about 9 of the 20 Ah
+	b = newBattery(cfg)
+	var charge float64 // Ah
+	for i := 0; i < 600; i++ {
+		b.step(20000, 1)
+		charge += b.current / 3600
+	}
+	if drained := (0.95 - b.soc) * cfg.Capacity; math.Abs(drained-charge) > 1e-9 || drained < 8.5 || drained > 9.5 {
+		t.Errorf("drained %g Ah for %g Ah drawn, want them equal and about 9", drained, charge)
+	}
+
+	// The losses heat the cells, which cool back toward ambient at rest
+	hot := b.temperature
+	if want := cfg.AmbientTemp + 0.5; hot < want {
+		t.Errorf("after 10 minutes at 20 kW: %g °C, want above %g", hot, want)
+	}
+	for i := 0; i < 600; i++ {
+		b.step(0, 1)
+	}
+	if b.temperature >= hot || b.temperature < cfg.AmbientTemp {
+		t.Errorf("after 10 minutes at rest: %g °C, want between ambient and %g", b.temperature, hot)
+	}
+
+	// The current never exceeds the rating, whatever the demand
+	b = newBattery(cfg)
+	ocv = b.openCircuitVoltage()
+	b.step(100000, 0.01)
+	if b.current != cfg.MaxCurrent || b.voltage != ocv-cfg.MaxCurrent*cfg.InternalResistance {
+		t.Errorf("under 100 kW: %g A at %g V, want the rated %g A", b.current, b.voltage, cfg.MaxCurrent)
+	}
+
+	// An empty pack supplies nothing
+	b.soc = 0
+	b.step(20000, 1)
+	if !b.empty() || b.current != 0 || b.soc != 0 {
+		t.Errorf("empty pack: %g A, SoC %g, want no current", b.current, b.soc)
+	}
+}
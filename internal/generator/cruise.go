@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"math"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+)
+
+/*
+cruise is the closed-loop speed controller that turns the speed error into a throttle in [-1, 1],
+positive for traction and negative for braking:
+- proportional: kp × error.
+- integral: ki × accumulated error, which removes the steady-state error drag and rolling resistance leave.
+  It only accumulates while the output is not saturated, or while the error pulls it back out (anti-windup).
+- derivative: kd × the rate of change of the measured speed, so a new target does not kick the throttle.
+*/
+type cruise struct {
+	gains     config.PID
+	integral  float64 // km/h·s
+	lastSpeed float64 // km/h, measured speed of the last update
+	hasLast   bool
+	err       float64 // km/h, speed error of the last update
+	throttle  float64 // output of the last update
+}
+
+// newCruise builds the controller of mode. A mode without PID gains is driven by throttleGain alone.
+func newCruise(mode config.Mode, throttleGain float64) *cruise {
+	c := &cruise{}
+	c.setGains(mode.PID, throttleGain)
+	return c
+}
+
+// setGains switches to gains, e.g. on a mode change, and restarts the integral term.
+func (c *cruise) setGains(gains config.PID, throttleGain float64) {
+	if gains == (config.PID{}) {
+		gains.Kp = throttleGain
+	}
+	c.gains = gains
+	c.reset()
+}
+
+// reset forgets the history of the controller, when it is not driving the vehicle.
+func (c *cruise) reset() {
+	c.integral, c.hasLast, c.err, c.throttle = 0, false, 0, 0
+}
+
+// update returns the throttle that drives speed toward target (both km/h) over a step of dt seconds.
+func (c *cruise) update(target, speed, dt float64) float64 {
+	c.err = target - speed
+
+	var derivative float64
+	if c.hasLast && dt > 0 {
+		derivative = -(speed - c.lastSpeed) / dt
+	}
+	c.lastSpeed, c.hasLast = speed, true
+
+	output := c.gains.Kp*c.err + c.gains.Ki*(c.integral+c.err*dt) + c.gains.Kd*derivative
+	saturated := (output > 1 && c.err > 0) || (output < -1 && c.err < 0)
+	if !saturated {
+		c.integral += c.err * dt
+	}
+
+	c.throttle = math.Max(-1, math.Min(1, c.gains.Kp*c.err+c.gains.Ki*c.integral+c.gains.Kd*derivative))
+	return c.throttle
+}
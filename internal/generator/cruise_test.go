@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+func TestCruiseHoldsTargetSpeed(t *testing.T) {
+	vehicle := goldenVehicle
+	vehicle.Sensor.Modes = []config.Mode{{
+		Name: "normal", SpeedCap: 0.8, GrowthFactor: 1, AccelerationLimit: 3, HeatingTau: 60, CoolingTau: 120,
+		PID: config.PID{Kp: 0.2, Ki: 0.05},
+	}}
+	sim := newSimulator(vehicle, rand.New(rand.NewSource(1)), nil)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	interval := vehicle.Sensor.Interval
+
+	// run steps the vehicle for d and returns its highest speed meanwhile
+	run := func(d time.Duration) (peak float32) {
+		for end := now.Add(d); now.Before(end); {
+			now = now.Add(interval)
+			sim.step(now, interval)
+			if sim.speed > peak {
+				peak = sim.speed
+			}
+		}
+		return peak
+	}
+
+	sim.handle(model.Command{Action: "start"}, now)
+	sim.handle(model.Command{Action: "set_speed", Params: 80.0}, now)
+	if peak := run(60 * time.Second); peak > 81 {
+		t.Errorf("overshoot to %.2f km/h holding 80 km/h", peak)
+	}
+	if state := sim.snapshot(now); state.SpeedError > 0.1 || state.SpeedError < -0.1 {
+		t.Errorf("speed error %.2f km/h after 60s at 80 km/h, want |error| <= 0.1", state.SpeedError)
+	}
+
+	// Slowing down brakes toward the new target without stopping
+	sim.handle(model.Command{Action: "set_speed", Params: 40.0}, now)
+	run(30 * time.Second)
+	if sim.speed < 39.9 || sim.speed > 40.1 {
+		t.Errorf("speed %.2f km/h after set_speed 40, want 40 ± 0.1", sim.speed)
+	}
+
+	// A target above the cap of the mode is held at the cap
+	sim.handle(model.Command{Action: "set_speed", Params: 200.0}, now)
+	run(60 * time.Second)
+	if limit := vehicle.Sensor.MaxSpeed * 0.8; sim.speed > limit+0.5 {
+		t.Errorf("speed %.2f km/h above the mode cap of %.1f km/h", sim.speed, limit)
+	}
+}
@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"math"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+)
+
+const (
+	gravity = 9.81 // m/s²
+	kmhToMs = 1 / 3.6
+)
+
+/*
+dynamics integrates the longitudinal motion of the vehicle.
+Each step the net force is
+- traction: throttle × maxTractionForce, when throttle > 0
+- brake:    throttle × maxBrakeForce, when throttle < 0
+- drag:     ½ · airDensity · dragCoefficient · frontalArea · v²
+- rolling:  rollingResistance · mass · g, only while moving
+and speed evolves as v += F/m · dt, never going below zero.
+Traction is reduced so that the acceleration never exceeds the limit of the driving mode, when it has one.
+*/
+type dynamics struct {
+	cfg      config.Dynamics
+	speed    float64 // m/s
+	traction float64 // N, propulsive force of the last step
+}
+
+func newDynamics(cfg config.Dynamics) *dynamics {
+	return &dynamics{cfg: cfg}
+}
+
+/*
+step advances the model by dt seconds with throttle in [-1, 1] and returns the speed in km/h.
+accelerationLimit (m/s²) caps the acceleration traction can produce; 0 means no limit.
+*/
+func (d *dynamics) step(throttle, accelerationLimit, dt float64) float64 {
+	throttle = math.Max(-1, math.Min(1, throttle))
+
+	// Resistive forces, opposing motion
+	var resistance float64
+	if d.speed > 0 {
+		resistance += 0.5 * d.cfg.AirDensity * d.cfg.DragCoefficient * d.cfg.FrontalArea * d.speed * d.speed
+		resistance += d.cfg.RollingResistance * d.cfg.Mass * gravity
+	}
+
+	// Propulsive or braking force
+	var force float64
+	if throttle >= 0 {
+		force = throttle * d.cfg.MaxTractionForce
+		if accelerationLimit > 0 {
+			force = math.Min(force, accelerationLimit*d.cfg.Mass+resistance)
+		}
+	} else {
+		force = throttle * d.cfg.MaxBrakeForce
+	}
+	d.traction = math.Max(0, force)
+	force -= resistance
+
+	d.speed += force / d.cfg.Mass * dt
+	if d.speed < 0 {
+		d.speed = 0 // brakes and resistance stop the vehicle, they never reverse it
+	}
+	return d.speedKmh()
+}
+
+// power returns the mechanical power (W) delivered by traction in the last step.
+func (d *dynamics) power() float64 {
+	return d.traction * d.speed
+}
+
+// stop brings the vehicle to rest at once, e.g. against the buffer at the end of the track.
+func (d *dynamics) stop() {
+	d.speed, d.traction = 0, 0
+}
+
+// speedKmh returns the current speed in km/h.
+func (d *dynamics) speedKmh() float64 {
+	return d.speed / kmhToMs
+}
@@ -0,0 +1,62 @@
+package generator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDynamics(t *testing.T) {
+	cfg := goldenVehicle.Sensor.Dynamics
+	const dt = 0.01
+
+	// Full throttle settles where traction balances drag and rolling resistance
+	d := newDynamics(cfg)
+	for i := 0; i < 120/dt; i++ {
+		d.step(1, 0, dt)
+	}
+	terminal := math.Sqrt((cfg.MaxTractionForce - cfg.RollingResistance*cfg.Mass*gravity) / (0.5 * cfg.AirDensity * cfg.DragCoefficient * cfg.FrontalArea))
+	if math.Abs(d.speed-terminal) > terminal*0.001 {
+		t.Errorf("terminal velocity = %.2f m/s, want %.2f", d.speed, terminal)
+	}
+	if want := cfg.MaxTractionForce * d.speed; math.Abs(d.power()-want) > 1e-6 {
+		t.Errorf("power = %.0f W, want traction × speed = %.0f", d.power(), want)
+	}
+
+	// The acceleration limit of the mode holds whatever the resistance
+	d = newDynamics(cfg)
+	d.speed = 20
+	d.step(1, 2, 1)
+	if math.Abs(d.speed-22) > 1e-9 {
+		t.Errorf("speed after 1 s limited to 2 m/s² = %.3f m/s, want 22", d.speed)
+	}
+
+	// Braking stops the vehicle in about v/(brake/m) and never reverses it
+	d = newDynamics(cfg)
+	d.speed = 30
+	steps := 0
+	for d.speed > 0 {
+		d.step(-1, 0, dt)
+		steps++
+	}
+	if stop, bound := float64(steps)*dt, 30/(cfg.MaxBrakeForce/cfg.Mass); stop > bound+dt || stop < bound*0.9 {
+		t.Errorf("stopped from 30 m/s in %.2f s, want just under %.2f s", stop, bound)
+	}
+	if kmh := d.step(-1, 0, dt); kmh != 0 || d.power() != 0 {
+		t.Errorf("braking at rest = %g km/h, %g W, want 0", kmh, d.power())
+	}
+
+	// Coasting only slows down, through drag and rolling resistance
+	d = newDynamics(cfg)
+	d.speed = 10
+	prev := d.speed
+	for i := 0; i < 100; i++ {
+		d.step(0, 0, dt)
+		if d.speed >= prev {
+			t.Fatalf("coasting step %d: %.4f m/s after %.4f, want slower", i, d.speed, prev)
+		}
+		prev = d.speed
+	}
+	if kmh := d.speedKmh(); math.Abs(kmh-d.speed*3.6) > 1e-9 {
+		t.Errorf("speedKmh = %g, want %g", kmh, d.speed*3.6)
+	}
+}
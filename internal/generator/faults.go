@@ -0,0 +1,224 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+// faultAllChannels targets every sensor channel at once.
+const faultAllChannels = "all"
+
+// timedValue is a past reading of a channel, kept for delay faults.
+type timedValue struct {
+	at    time.Time
+	value float32
+}
+
+// activeFault is an injected Fault together with the per-channel state it needs.
+type activeFault struct {
+	model.Fault
+	stuck   map[string]float32      // stuck: value frozen at injection, by channel
+	history map[string][]timedValue // delay: recent readings, by channel
+}
+
+// faultInjector keeps the active Faults of a simulator and applies them to readings.
+type faultInjector struct {
+	cfg    config.SensorConfig // channel ranges, for the default fault magnitudes
+	rng    *rand.Rand          // draws the spikes
+	active []*activeFault
+}
+
+/*
+parseFault builds a Fault from the params of a "fault" Command, e.g.
+{"type": "stuck", "channel": "pressure", "duration": "5s", "value": 3.2}.
+- type: dropout | stuck | spike | nan | drift | delay, or "clear" to remove the active faults of channel.
+- channel: one of sensorChannels or "all" (default).
+- duration: Go duration; omitted means until cleared.
+- value: stuck value (default: the reading at injection), spike magnitude (default: 25% of the channel range),
+  drift rate per second (default: 1% of the channel range) or delay in seconds (default: 1).
+- probability: chance of a spike per sample (default: 0.05).
+*/
+func parseFault(params interface{}, now time.Time) (model.Fault, error) {
+	p, ok := params.(map[string]interface{})
+	if !ok {
+		return model.Fault{}, errors.New("params must be an object")
+	}
+
+	fault := model.Fault{Channel: faultAllChannels, StartedAt: now}
+	fault.Type, _ = p["type"].(string)
+	fault.Type = strings.ToLower(fault.Type)
+	switch fault.Type {
+	case model.FaultDropout, model.FaultStuck, model.FaultSpike, model.FaultNaN, model.FaultDrift, model.FaultDelay, "clear":
+	default:
+		return fault, fmt.Errorf("unknown fault type %q", fault.Type)
+	}
+
+	if channel, ok := p["channel"].(string); ok && !strings.EqualFold(channel, faultAllChannels) {
+		if fault.Channel, ok = sensorChannel(channel); !ok {
+			return fault, fmt.Errorf("unknown channel %q", channel)
+		}
+	}
+
+	if duration, ok := p["duration"].(string); ok {
+		d, err := time.ParseDuration(duration)
+		if err != nil || d <= 0 {
+			return fault, fmt.Errorf("invalid duration %q", duration)
+		}
+		fault.ExpiresAt = now.Add(d)
+	}
+
+	if value, ok := p["value"].(float64); ok {
+		fault.Value = &value
+	}
+	fault.Probability, _ = p["probability"].(float64)
+	if fault.Type == model.FaultSpike && fault.Probability <= 0 {
+		fault.Probability = 0.05
+	}
+	if fault.Type == model.FaultDelay && (fault.Value == nil || *fault.Value <= 0) {
+		delay := 1.0
+		fault.Value = &delay
+	}
+
+	return fault, nil
+}
+
+// add activates fault.
+func (f *faultInjector) add(fault model.Fault) {
+	f.active = append(f.active, &activeFault{
+		Fault:   fault,
+		stuck:   make(map[string]float32),
+		history: make(map[string][]timedValue),
+	})
+}
+
+// clear removes the active faults of channel ("all" removes every fault) and returns them.
+func (f *faultInjector) clear(channel string) []model.Fault {
+	return f.remove(func(a *activeFault) bool {
+		return channel == faultAllChannels || a.Channel == channel
+	})
+}
+
+// expire removes the faults whose duration elapsed at now and returns them.
+func (f *faultInjector) expire(now time.Time) []model.Fault {
+	return f.remove(func(a *activeFault) bool {
+		return !a.ExpiresAt.IsZero() && !now.Before(a.ExpiresAt)
+	})
+}
+
+func (f *faultInjector) remove(match func(*activeFault) bool) []model.Fault {
+	var removed []model.Fault
+	kept := f.active[:0]
+	for _, a := range f.active {
+		if match(a) {
+			removed = append(removed, a.Fault)
+		} else {
+			kept = append(kept, a)
+		}
+	}
+	f.active = kept
+	return removed
+}
+
+// list returns the active faults.
+func (f *faultInjector) list() []model.Fault {
+	faults := make([]model.Fault, 0, len(f.active))
+	for _, a := range f.active {
+		faults = append(faults, a.Fault)
+	}
+	return faults
+}
+
+// dropsFrame reports whether an active dropout silences every channel.
+func (f *faultInjector) dropsFrame() bool {
+	for _, a := range f.active {
+		if a.Type == model.FaultDropout && a.Channel == faultAllChannels {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the reading of channel at now after every active fault that targets it.
+func (f *faultInjector) apply(channel string, value float32, now time.Time) float32 {
+	for _, a := range f.active {
+		if a.Channel != channel && a.Channel != faultAllChannels {
+			continue
+		}
+
+		switch a.Type {
+		case model.FaultDropout:
+			value = 0
+
+		case model.FaultStuck:
+			if a.Value != nil {
+				value = float32(*a.Value)
+				break
+			}
+			if _, ok := a.stuck[channel]; !ok {
+				a.stuck[channel] = value
+			}
+			value = a.stuck[channel]
+
+		case model.FaultSpike:
+			if f.rng.Float64() < a.Probability {
+				magnitude := 0.25 * channelRange(f.cfg, channel)
+				if a.Value != nil {
+					magnitude = float32(*a.Value)
+				}
+				if f.rng.Intn(2) == 0 {
+					magnitude = -magnitude
+				}
+				value += magnitude
+			}
+
+		case model.FaultNaN:
+			value = float32(math.NaN())
+
+		case model.FaultDrift:
+			rate := 0.01 * channelRange(f.cfg, channel)
+			if a.Value != nil {
+				rate = float32(*a.Value)
+			}
+			value += rate * float32(now.Sub(a.StartedAt).Seconds())
+
+		case model.FaultDelay:
+			// Keep readings for the delay window and report the oldest one that is old enough
+			delay := time.Duration(*a.Value * float64(time.Second))
+			history := append(a.history[channel], timedValue{at: now, value: value})
+			i := 0
+			for i+1 < len(history) && !history[i+1].at.After(now.Add(-delay)) {
+				i++
+			}
+			history = history[i:]
+			a.history[channel] = history
+			value = history[0].value
+		}
+	}
+	return value
+}
+
+// channelRange returns the configured span of a sensor channel.
+func channelRange(cfg config.SensorConfig, channel string) float32 {
+	switch channel {
+	case "speed":
+		return cfg.MaxSpeed - cfg.MinSpeed
+	case "pressure":
+		return cfg.MaxPressure - cfg.MinPressure
+	case "temperature", "batteryTemperature":
+		return cfg.MaxTemp - cfg.MinTemp
+	case "batteryVoltage":
+		return float32(cfg.Battery.MaxVoltage - cfg.Battery.MinVoltage)
+	case "batteryCurrent":
+		return float32(cfg.Battery.MaxCurrent)
+	case "batterySoC":
+		return 100
+	}
+	return 1
+}
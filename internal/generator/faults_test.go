@@ -0,0 +1,173 @@
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+func TestParseFault(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		params      interface{}
+		channel     string
+		value       *float64
+		probability float64
+		expiresAt   time.Time
+		err         bool
+	}{
+		{name: "not an object", params: "stuck", err: true},
+		{name: "unknown type", params: map[string]interface{}{"type": "melt"}, err: true},
+		{name: "unknown channel", params: map[string]interface{}{"type": "nan", "channel": "altitude"}, err: true},
+		{name: "invalid duration", params: map[string]interface{}{"type": "nan", "duration": "soon"}, err: true},
+		{name: "negative duration", params: map[string]interface{}{"type": "nan", "duration": "-1s"}, err: true},
+		{name: "every channel by default", params: map[string]interface{}{"type": "NaN"}, channel: "all"},
+		{name: "channel and duration", params: map[string]interface{}{"type": "dropout", "channel": "Pressure", "duration": "5s"}, channel: "pressure", expiresAt: now.Add(5 * time.Second)},
+		{name: "stuck without a value", params: map[string]interface{}{"type": "stuck", "channel": "speed"}, channel: "speed"},
+		{name: "stuck at zero", params: map[string]interface{}{"type": "stuck", "value": 0.0}, channel: "all", value: ptr(0.0)},
+		{name: "spike probability", params: map[string]interface{}{"type": "spike"}, channel: "all", probability: 0.05},
+		{name: "delay of a second", params: map[string]interface{}{"type": "delay", "value": -2.0}, channel: "all", value: ptr(1.0)},
+		{name: "clear", params: map[string]interface{}{"type": "clear", "channel": "speed"}, channel: "speed"},
+	}
+
+	for _, tt := range tests {
+		fault, err := parseFault(tt.params, now)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: parseFault succeeded, want an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if fault.Channel != tt.channel || fault.Probability != tt.probability || !fault.ExpiresAt.Equal(tt.expiresAt) || !fault.StartedAt.Equal(now) {
+			t.Errorf("%s: got %+v, want channel %s, probability %g, expiring at %s", tt.name, fault, tt.channel, tt.probability, tt.expiresAt)
+		}
+		if (fault.Value == nil) != (tt.value == nil) || (fault.Value != nil && *fault.Value != *tt.value) {
+			t.Errorf("%s: value %v, want %v", tt.name, fault.Value, tt.value)
+		}
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
+
+func TestFaultEffects(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	inject := func(params map[string]interface{}) *faultInjector {
+		f := &faultInjector{cfg: goldenVehicle.Sensor, rng: rand.New(rand.NewSource(1))}
+		fault, err := parseFault(params, start)
+		if err != nil {
+			t.Fatalf("parseFault(%v): %v", params, err)
+		}
+		f.add(fault)
+		return f
+	}
+
+	// Dropout reads 0, and silences the whole frame only on every channel
+	f := inject(map[string]interface{}{"type": "dropout", "channel": "speed"})
+	if got := f.apply("speed", 50, start); got != 0 || f.dropsFrame() {
+		t.Errorf("speed dropout: %g, drops frame %t, want 0 and false", got, f.dropsFrame())
+	}
+	if got := f.apply("pressure", 3, start); got != 3 {
+		t.Errorf("speed dropout changed pressure to %g", got)
+	}
+	if !inject(map[string]interface{}{"type": "dropout"}).dropsFrame() {
+		t.Error("dropout on every channel does not drop the frame")
+	}
+
+	// Stuck freezes at the reading at injection, or at the given value even if 0
+	f = inject(map[string]interface{}{"type": "stuck", "channel": "speed"})
+	for i, v := range []float32{50, 60, 70} {
+		if got := f.apply("speed", v, start.Add(time.Duration(i)*time.Second)); got != 50 {
+			t.Errorf("stuck reading %d = %g, want 50", i, got)
+		}
+	}
+	f = inject(map[string]interface{}{"type": "stuck", "value": 0.0})
+	if got := f.apply("temperature", 35, start); got != 0 {
+		t.Errorf("stuck at 0 read %g, want 0", got)
+	}
+
+	// Spike jumps by ±value with its probability
+	f = inject(map[string]interface{}{"type": "spike", "value": 5.0, "probability": 1.0})
+	for i := 0; i < 10; i++ {
+		if got := f.apply("speed", 10, start); got != 5 && got != 15 {
+			t.Fatalf("spike read %g, want 10 ± 5", got)
+		}
+	}
+	f = inject(map[string]interface{}{"type": "spike", "probability": 1.0})
+	if got, magnitude := f.apply("speed", 10, start), 0.25*channelRange(goldenVehicle.Sensor, "speed"); math.Abs(float64(got-10)) != float64(magnitude) {
+		t.Errorf("spike without a value read %g, want 10 ± %g (25%% of the range)", got, magnitude)
+	}
+
+	// NaN
+	if got := inject(map[string]interface{}{"type": "nan"}).apply("pressure", 3, start); !math.IsNaN(float64(got)) {
+		t.Errorf("nan fault read %g", got)
+	}
+
+	// Drift moves away by value units per second since injection
+	f = inject(map[string]interface{}{"type": "drift", "value": 2.0})
+	if got := f.apply("temperature", 30, start.Add(3*time.Second)); got != 36 {
+		t.Errorf("drift after 3 s read %g, want 30 + 2×3 = 36", got)
+	}
+
+	// Delay reads the value from value seconds ago, or the oldest one until then
+	f = inject(map[string]interface{}{"type": "delay", "value": 0.5})
+	for i := 0; i <= 10; i++ {
+		want := float32(max(0, i-5))
+		if got := f.apply("speed", float32(i), start.Add(time.Duration(i)*100*time.Millisecond)); got != want {
+			t.Fatalf("delayed reading at %d00 ms = %g, want %g", i, got, want)
+		}
+	}
+}
+
+func TestFaultExpiryAndClear(t *testing.T) {
+	var messages []string
+	sim := newSimulator(goldenVehicle, rand.New(rand.NewSource(1)), func(e model.Event) {
+		if e.Type == model.EventFault {
+			messages = append(messages, e.Message)
+		}
+	})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	interval := goldenVehicle.Sensor.Interval
+
+	// A fault with a duration expires on the simulated time of the steps
+	sim.handle(model.Command{Action: "fault", Params: map[string]interface{}{"type": "dropout", "duration": "300ms"}}, now)
+	end := now.Add(300 * time.Millisecond)
+	for now.Before(end) {
+		if _, ok := sim.step(now, interval); ok {
+			t.Fatalf("reading emitted at %s during the dropout", now.Format("15:04:05.000"))
+		}
+		now = now.Add(interval)
+	}
+	if _, ok := sim.step(now, interval); !ok || len(sim.faults.list()) != 0 {
+		t.Fatalf("dropout still active at its expiry: %+v", sim.faults.list())
+	}
+	if len(messages) != 2 || !strings.HasPrefix(messages[1], "Expired dropout") {
+		t.Errorf("fault events = %q, want injected and expired", messages)
+	}
+
+	// Clear removes the faults of a channel, or every fault on "all"
+	for _, channel := range []string{"speed", "speed", "pressure", "all"} {
+		sim.handle(model.Command{Action: "fault", Params: map[string]interface{}{"type": "nan", "channel": channel}}, now)
+	}
+	messages = nil
+	sim.handle(model.Command{Action: "fault", Params: map[string]interface{}{"type": "clear", "channel": "speed"}}, now)
+	if faults := sim.faults.list(); len(faults) != 2 || len(messages) != 2 {
+		t.Errorf("after clearing speed: %d faults and %d events, want 2 and 2", len(faults), len(messages))
+	}
+	sim.handle(model.Command{Action: "fault", Params: map[string]interface{}{"type": "clear"}}, now)
+	if faults := sim.faults.list(); len(faults) != 0 || len(messages) != 4 {
+		t.Errorf("after clearing all: %d faults and %d events, want 0 and 4", len(faults), len(messages))
+	}
+	if data, ok := sim.step(now.Add(interval), interval); !ok || math.IsNaN(float64(data.Speed)) {
+		t.Errorf("reading after clearing = %+v, want no NaN", data)
+	}
+}
@@ -1,24 +1,102 @@
 package generator
 
 import (
+	"context"
+	"hash/fnv"
 	"log"
 
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/clock"
 	"github.com/vasyl-ks/TM-software-H11/internal/model"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
 )
 
 /*
-Generator initializes the dataChan channel, then calls the Sensor and Process goroutines.
-- Sensor runs independently, generates random values, SensorData, and sends it through dataChan.
-  - Sensor also receives Command messages via inCommandChan to modify its behavior in real time.
-- Process receives SensorData, calculates statistics, builds a Result, and sends it through outResultChan.
+Generator runs an independent SensorSource and Process pair for every vehicle in config.Vehicles.
+- The SensorSource of the vehicle (see source.go) runs independently and sends its SensorData through its vehicle's dataQueue.
+  - By default it is the simulated Sensor, which generates random values and receives Command messages
+    to modify its behavior in real time.
+  - Sensor pushes state changes, fault notifications and trips through outEventQueue.
+- Process receives SensorData, calculates statistics, builds a Result, and sends it through resultQueue.
+  - Process pushes the alarms of the alert rules through outEventQueue.
+- While switched on, by config.Raw or the "raw" Command, Process also forwards the raw SensorData in frames through outFrameQueue.
+- Detect, shared by every vehicle, checks each Result for anomalies and forwards it through outResultQueue.
+  - Detect pushes the anomalies it finds through outEventQueue.
+- Each simulated vehicle draws from its own random generator, seeded from config.Simulation.Seed and its ID.
+- When config.Replay.Path is set, Replay feeds the recording instead, through a "replay" source per recorded vehicle.
+- Commands from inCommandChan are routed by their VehicleID; a Command without one goes to every vehicle.
+  "raw" Commands switch the raw stream instead.
 */
-func Run(inCommandChan <-chan model.Command, outResultChan chan<- model.ResultData) {
-	defer log.Println("[INFO][Generator] Running.")
+func Run(inCommandChan <-chan model.Command, outResultQueue *queue.Queue[model.ResultData], outEventQueue *queue.Queue[model.Event], outFrameQueue *queue.Queue[model.SensorFrame]) {
+	// Every Result goes through the anomaly detector on its way out
+	resultQueue := queue.New[model.ResultData]("generator.result")
+	go Detect(resultQueue.Out(), outResultQueue, outEventQueue)
+	raw := newRawStream(config.Raw, outFrameQueue)
 
-	// Create unbuffered channel.
-	dataChan := make(chan model.SensorData)
+	// A recording replaces the simulated vehicles
+	if config.Replay.Path != "" {
+		Replay(inCommandChan, resultQueue, outEventQueue, raw)
+		return
+	}
 
-	// Launch concurrent goroutines.
-	go Sensor(inCommandChan, dataChan)
-	go Process(dataChan, outResultChan)
+	runVehicles(config.Vehicles, nil, inCommandChan, resultQueue, outEventQueue, raw)
+}
+
+/*
+runVehicles runs the SensorSource and Process of every vehicle, pushing their Results to resultQueue,
+and routes the Commands from inCommandChan to them until it is closed.
+The sources are handed recordings, the recordings already loaded by path, if any.
+*/
+func runVehicles(vehicles []config.VehicleConfig, recordings map[string][]record, inCommandChan <-chan model.Command, resultQueue *queue.Queue[model.ResultData], outEventQueue *queue.Queue[model.Event], raw *rawStream) {
+	commandQueues := make(map[string]*queue.Queue[model.Command], len(vehicles))
+
+	// Every vehicle shares the simulated start time
+	env := SourceEnv{Start: clock.Now(), Events: outEventQueue, Results: resultQueue, recordings: recordings}
+	ctx := context.Background()
+
+	for _, vehicle := range vehicles {
+		source, err := NewSource(vehicle, env)
+		if err != nil {
+			log.Printf("[ERROR][Generator] Skipping vehicle %s: %v", vehicle.VehicleID, err)
+			continue
+		}
+
+		// Create bounded queues.
+		dataQueue := queue.NewInstance[model.SensorData]("generator.sensorData", vehicle.VehicleID)
+		commandQueue := queue.NewInstance[model.Command]("generator.command", vehicle.VehicleID)
+		commandQueues[vehicle.VehicleID] = commandQueue
+
+		// Launch concurrent goroutines.
+		go source.Run(ctx, commandQueue.Out(), dataQueue)
+		go Process(vehicle, dataQueue.Out(), resultQueue, outEventQueue, raw)
+	}
+
+	log.Printf("[INFO][Generator] Running %d vehicles.", len(commandQueues))
+
+	// Route commands to their vehicles
+	for cmd := range inCommandChan {
+		if raw.handle(cmd) {
+			continue
+		}
+		if cmd.VehicleID == "" {
+			for _, q := range commandQueues {
+				q.Push(cmd)
+			}
+			continue
+		}
+
+		q, ok := commandQueues[cmd.VehicleID]
+		if !ok {
+			log.Printf("[ERROR][Generator] Command %s for unknown vehicle %s.", cmd.Action, cmd.VehicleID)
+			continue
+		}
+		q.Push(cmd)
+	}
+}
+
+// vehicleSeed derives the seed of a vehicle, so vehicles sharing the simulation seed still differ.
+func vehicleSeed(seed int64, vehicleID string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(vehicleID))
+	return seed ^ int64(h.Sum64())
 }
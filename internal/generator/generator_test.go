@@ -0,0 +1,128 @@
+package generator
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
+)
+
+func TestVehicleSeed(t *testing.T) {
+	tests := []struct {
+		name      string
+		seed      int64
+		a, b      string
+		wantEqual bool
+	}{
+		{name: "the same vehicle and seed", seed: 1, a: "123", b: "123", wantEqual: true},
+		{name: "vehicles sharing a seed", seed: 1, a: "123", b: "124"},
+		{name: "vehicles without a seed", seed: 0, a: "123", b: "124"},
+		{name: "an empty and a named vehicle", seed: 1, a: "", b: "1"},
+	}
+	for _, tt := range tests {
+		if equal := vehicleSeed(tt.seed, tt.a) == vehicleSeed(tt.seed, tt.b); equal != tt.wantEqual {
+			t.Errorf("%s (%q, %q, seed %d): equal seeds %t, want %t", tt.name, tt.a, tt.b, tt.seed, equal, tt.wantEqual)
+		}
+	}
+	if vehicleSeed(1, "123") == vehicleSeed(2, "123") {
+		t.Error("the simulation seed does not change the seed of a vehicle")
+	}
+}
+
+// routedCommand is a Command as received by the source of a vehicle.
+type routedCommand struct {
+	vehicleID string
+	action    string
+}
+
+// recordingSource forwards the Commands its vehicle receives to got.
+type recordingSource struct {
+	vehicleID string
+	got       chan<- routedCommand
+}
+
+func (s recordingSource) Run(ctx context.Context, commands <-chan model.Command, out *queue.Queue[model.SensorData]) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case cmd := <-commands:
+			s.got <- routedCommand{s.vehicleID, cmd.Action}
+		}
+	}
+}
+
+func TestRunVehiclesRoutesCommands(t *testing.T) {
+	defer func(interval time.Duration) { config.Processor.Interval = interval }(config.Processor.Interval)
+	config.Processor.Interval = 500 * time.Millisecond
+
+	got := make(chan routedCommand, 100)
+	RegisterSource("recording", func(v config.VehicleConfig, env SourceEnv) (SensorSource, error) {
+		return recordingSource{v.VehicleID, got}, nil
+	})
+	defer func() {
+		sourcesMu.Lock()
+		delete(sources, "recording")
+		sourcesMu.Unlock()
+	}()
+
+	var vehicles []config.VehicleConfig
+	for _, id := range []string{"a", "b"} {
+		vehicles = append(vehicles, config.VehicleConfig{VehicleID: id, Sensor: goldenVehicle.Sensor, Source: config.SourceConfig{Type: "recording"}})
+	}
+	vehicles = append(vehicles, config.VehicleConfig{VehicleID: "c", Source: config.SourceConfig{Type: "hardware"}})
+
+	commands := make(chan model.Command)
+	done := make(chan struct{})
+	go func() {
+		runVehicles(vehicles, nil, commands, queue.New[model.ResultData]("test.results"), queue.New[model.Event]("test.events"), newRawStream(config.RawConfig{}, queue.New[model.SensorFrame]("test.frames")))
+		close(done)
+	}()
+
+	// One vehicle, every vehicle, an unknown (or skipped) one, and a raw Command the vehicles never see
+	for _, cmd := range []model.Command{
+		{Action: "start", VehicleID: "a"},
+		{Action: "stop"},
+		{Action: "start", VehicleID: "c"},
+		{Action: "raw", Params: true},
+		{Action: "accelerate", VehicleID: "b"},
+	} {
+		commands <- cmd
+	}
+	close(commands)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runVehicles kept running after its commands were closed")
+	}
+
+	var routed []routedCommand
+	for timeout := time.After(200 * time.Millisecond); ; {
+		select {
+		case r := <-got:
+			routed = append(routed, r)
+			continue
+		case <-timeout:
+		}
+		break
+	}
+	sort.Slice(routed, func(i, j int) bool {
+		if routed[i].vehicleID != routed[j].vehicleID {
+			return routed[i].vehicleID < routed[j].vehicleID
+		}
+		return routed[i].action > routed[j].action
+	})
+	want := []routedCommand{{"a", "stop"}, {"a", "start"}, {"b", "stop"}, {"b", "accelerate"}}
+	if len(routed) != len(want) {
+		t.Fatalf("routed %v, want %v", routed, want)
+	}
+	for i := range want {
+		if routed[i] != want[i] {
+			t.Fatalf("routed %v, want %v", routed, want)
+		}
+	}
+}
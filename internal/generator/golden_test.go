@@ -0,0 +1,104 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// goldenVehicle is a self-contained vehicle, so the golden run does not depend on config.json.
+var goldenVehicle = config.VehicleConfig{
+	VehicleID: "golden",
+	Sensor: config.SensorConfig{
+		Interval:    10 * time.Millisecond,
+		MaxSpeed:    150,
+		MaxPressure: 10,
+		MaxTemp:     50,
+		Modes:       []config.Mode{{Name: "normal", SpeedCap: 0.8, GrowthFactor: 1, HeatingTau: 60, CoolingTau: 120}},
+		DefaultMode: "normal",
+		Dynamics: config.Dynamics{
+			Mass: 300, MaxTractionForce: 1500, MaxBrakeForce: 3000, DragCoefficient: 0.4,
+			FrontalArea: 0.8, AirDensity: 1.225, RollingResistance: 0.01, ThrottleGain: 0.2,
+		},
+		Noise: map[string]config.Noise{
+			"speed":       {StdDev: 0.1, Resolution: 0.01},
+			"pressure":    {StdDev: 0.03, DriftRate: 0.001, Resolution: 0.01},
+			"temperature": {StdDev: 0.15, DriftRate: 0.005, Resolution: 0.1, Hold: 20 * time.Millisecond},
+		},
+		Battery: config.Battery{
+			Capacity: 20, MinVoltage: 300, MaxVoltage: 400, MaxCurrent: 100, InternalResistance: 0.15,
+			InitialSoC: 0.95, AuxiliaryCurrent: 1.5, DrivetrainEfficiency: 0.9, HeatCapacity: 20000,
+			CoolingCoefficient: 15, AmbientTemp: 20,
+		},
+		Thermal: config.Thermal{AmbientTemp: 20, RisePerKW: 2},
+	},
+}
+
+// goldenTimeline holds the commands applied before each tick.
+var goldenTimeline = map[int][]model.Command{
+	10:  {{Action: "start"}, {Action: "accelerate", Params: 40.0}},
+	80:  {{Action: "fault", Params: map[string]interface{}{"type": "spike", "channel": "pressure", "duration": "500ms", "probability": 0.5}}},
+	150: {{Action: "stop"}},
+}
+
+// runSeeded drives a simulator and a batcher tick by tick and returns every SensorData and ResultData as JSON lines.
+func runSeeded(seed int64) []byte {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	interval := goldenVehicle.Sensor.Interval
+
+	sim := newSimulator(goldenVehicle, rand.New(rand.NewSource(seed)), nil)
+	b := &batcher{interval: 250 * time.Millisecond, percentiles: []float64{95, 99}, vehicle: goldenVehicle}
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	for tick := 1; tick <= 200; tick++ {
+		now := start.Add(time.Duration(tick) * interval)
+		for _, cmd := range goldenTimeline[tick] {
+			sim.handle(cmd, now)
+		}
+		data, ok := sim.step(now, interval)
+		if !ok {
+			continue
+		}
+		encoder.Encode(data)
+		if result, ok := b.add(data); ok {
+			encoder.Encode(result)
+		}
+	}
+	return out.Bytes()
+}
+
+func TestSeededRunMatchesGolden(t *testing.T) {
+	got := runSeeded(42)
+	if again := runSeeded(42); !bytes.Equal(got, again) {
+		t.Fatal("two runs with the same seed differ")
+	}
+
+	path := filepath.Join("testdata", "seed42.golden.jsonl")
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("seeded run differs from %s; run with -update if the change is intended", path)
+	}
+
+	if bytes.Equal(got, runSeeded(43)) {
+		t.Error("runs with different seeds are identical")
+	}
+}
@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+func TestDrivingModes(t *testing.T) {
+	vehicle := goldenVehicle
+	vehicle.Sensor.Modes = []config.Mode{
+		{Name: "eco", SpeedCap: 0.5, GrowthFactor: 0.7, AccelerationLimit: 1, HeatingTau: 90, CoolingTau: 120},
+		{Name: "normal", SpeedCap: 0.8, GrowthFactor: 1, HeatingTau: 60, CoolingTau: 120},
+		{Name: "sport", SpeedCap: 1, GrowthFactor: 1.3, AccelerationLimit: 3, HeatingTau: 40, CoolingTau: 120},
+	}
+	vehicle.Sensor.DefaultMode = "normal"
+
+	tests := []struct {
+		mode       string // as sent in the "mode" command
+		want       string // mode the vehicle is in afterwards
+		rejected   bool
+		maxTarget  float32 // km/h, speed cap × maxSpeed
+		maxSpeedMS float64 // m/s after 1 s from rest, the acceleration limit × 1 s; 0 when unlimited
+	}{
+		{mode: "eco", want: "eco", maxTarget: 75, maxSpeedMS: 1},
+		{mode: "SPORT", want: "sport", maxTarget: 150, maxSpeedMS: 3},
+		{mode: "Normal", want: "normal", maxTarget: 120},
+		{mode: "race", want: "normal", rejected: true, maxTarget: 120},
+		{mode: "", want: "normal", rejected: true, maxTarget: 120},
+	}
+
+	for _, tt := range tests {
+		var rejections int
+		sim := newSimulator(vehicle, rand.New(rand.NewSource(1)), func(e model.Event) {
+			if e.Type == model.EventRejected {
+				rejections++
+			}
+		})
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		interval := vehicle.Sensor.Interval
+
+		sim.handle(model.Command{Action: "start"}, now)
+		sim.handle(model.Command{Action: "mode", Params: tt.mode}, now)
+		if sim.mode.Name != tt.want || (rejections == 1) != tt.rejected {
+			t.Errorf("mode %q: in %s with %d rejections, want %s, rejected %t", tt.mode, sim.mode.Name, rejections, tt.want, tt.rejected)
+			continue
+		}
+
+		// The target is capped by the mode and the acceleration limited by it
+		sim.handle(model.Command{Action: "accelerate", Params: 500.0}, now)
+		for end := now.Add(time.Second); now.Before(end); {
+			now = now.Add(interval)
+			sim.step(now, interval)
+		}
+		if sim.targetSpeed != tt.maxTarget {
+			t.Errorf("mode %q: target %.1f km/h, want capped at %.1f", tt.mode, sim.targetSpeed, tt.maxTarget)
+		}
+		if got := sim.dynamics.speed; tt.maxSpeedMS > 0 && (got > tt.maxSpeedMS+1e-6 || got < tt.maxSpeedMS*0.95) {
+			t.Errorf("mode %q: %.3f m/s after 1 s, want the limit of %g m/s²", tt.mode, got, tt.maxSpeedMS)
+		}
+		if got := sim.dynamics.speed; tt.maxSpeedMS == 0 && got <= 3 {
+			t.Errorf("mode %q: %.3f m/s after 1 s, want traction alone to beat the limited modes", tt.mode, got)
+		}
+	}
+
+	// A mode whose cap is below the current speed is refused while running
+	sim := newSimulator(vehicle, rand.New(rand.NewSource(1)), nil)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	sim.handle(model.Command{Action: "start"}, now)
+	sim.handle(model.Command{Action: "accelerate", Params: 100.0}, now)
+	for i := 0; i < 3000; i++ {
+		now = now.Add(vehicle.Sensor.Interval)
+		sim.step(now, vehicle.Sensor.Interval)
+	}
+	if reason := sim.reject(model.Command{Action: "mode", Params: "eco"}); reason == "" {
+		t.Errorf("eco (cap 75 km/h) accepted at %.1f km/h", sim.speed)
+	}
+	if reason := sim.reject(model.Command{Action: "mode", Params: "sport"}); reason != "" {
+		t.Errorf("sport rejected at %.1f km/h: %s", sim.speed, reason)
+	}
+}
@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+)
+
+/*
+noiseModel turns the true value of a sensor channel into what the sensor reads.
+Effects are applied in the order a real acquisition chain would:
+- bias: constant offset.
+- drift: random walk, growing by driftRate·√dt per step.
+- white noise: Gaussian with stdDev.
+- quantization: rounding to the ADC resolution.
+- sample-and-hold: the reading is refreshed only once per hold period.
+*/
+type noiseModel struct {
+	cfg       config.Noise
+	rng       *rand.Rand
+	drift     float64
+	held      float64
+	hasHeld   bool
+	sinceHeld float64 // seconds since the held value was sampled
+}
+
+func newNoiseModel(cfg config.Noise, rng *rand.Rand) *noiseModel {
+	return &noiseModel{cfg: cfg, rng: rng}
+}
+
+// apply returns the reading of value after a step of dt seconds.
+func (n *noiseModel) apply(value, dt float64) float64 {
+	// Drift keeps walking even while the output is held
+	if n.cfg.DriftRate > 0 {
+		n.drift += n.rng.NormFloat64() * n.cfg.DriftRate * math.Sqrt(dt)
+	}
+
+	// Sample-and-hold
+	n.sinceHeld += dt
+	if n.hasHeld && n.sinceHeld < n.cfg.Hold.Seconds() {
+		return n.held
+	}
+
+	reading := value + n.cfg.Bias + n.drift
+	if n.cfg.StdDev > 0 {
+		reading += n.rng.NormFloat64() * n.cfg.StdDev
+	}
+	if n.cfg.Resolution > 0 {
+		reading = math.Round(reading/n.cfg.Resolution) * n.cfg.Resolution
+	}
+
+	n.held, n.hasHeld, n.sinceHeld = reading, true, 0
+	return reading
+}
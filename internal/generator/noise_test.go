@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+)
+
+// meanStdDev returns the mean and sample standard deviation of values.
+func meanStdDev(values []float64) (mean, stdDev float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		stdDev += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(stdDev / float64(len(values)-1))
+}
+
+func TestNoiseModel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const dt = 0.01
+
+	// White noise and bias: readings center on value + bias with the configured sigma
+	n := newNoiseModel(config.Noise{StdDev: 0.5, Bias: 1}, rng)
+	readings := make([]float64, 20000)
+	for i := range readings {
+		readings[i] = n.apply(10, dt)
+	}
+	if mean, stdDev := meanStdDev(readings); math.Abs(mean-11) > 0.02 || math.Abs(stdDev-0.5) > 0.02 {
+		t.Errorf("white noise: mean %.3f, σ %.3f, want 11 and 0.5", mean, stdDev)
+	}
+
+	// Drift: after T seconds the random walk has spread by driftRate·√T
+	drifts := make([]float64, 2000)
+	for i := range drifts {
+		n := newNoiseModel(config.Noise{DriftRate: 2}, rng)
+		for step := 0; step < 400; step++ {
+			drifts[i] = n.apply(0, dt)
+		}
+	}
+	if mean, stdDev := meanStdDev(drifts); math.Abs(mean) > 0.3 || math.Abs(stdDev-4) > 0.25 {
+		t.Errorf("drift after 4 s: mean %.3f, σ %.3f, want 0 and 2·√4 = 4", mean, stdDev)
+	}
+
+	// Quantization rounds to the resolution
+	n = newNoiseModel(config.Noise{StdDev: 1, Resolution: 0.25}, rng)
+	for i := 0; i < 100; i++ {
+		if r := n.apply(3, dt); math.Abs(r/0.25-math.Round(r/0.25)) > 1e-9 {
+			t.Fatalf("reading %g is not a multiple of 0.25", r)
+		}
+	}
+
+	// Sample-and-hold refreshes the reading once per hold period
+	n = newNoiseModel(config.Noise{Hold: 30 * time.Millisecond}, rng)
+	var got []float64
+	for i := 1; i <= 7; i++ {
+		got = append(got, n.apply(float64(i), dt))
+	}
+	want := []float64{1, 1, 1, 4, 4, 4, 7}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Fatalf("held readings = %v, want %v", got, want)
+		}
+	}
+
+	// Without any effect the reading is the value
+	n = newNoiseModel(config.Noise{}, rng)
+	if r := n.apply(42.5, dt); r != 42.5 {
+		t.Errorf("noiseless reading = %g, want 42.5", r)
+	}
+}
@@ -1,153 +1,224 @@
 package generator
 
 import (
+	"fmt"
 	"log"
+	"math"
 	"time"
 
 	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/clock"
 	"github.com/vasyl-ks/TM-software-H11/internal/model"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
 )
 
-// getLastTime returns the latest timestamp from a slice of SensorData.
-func getLastTime(data []model.SensorData) time.Time {
-	maxTime := data[0].CreatedAt
-	for _, d := range data[1:] {
-		if d.CreatedAt.After(maxTime) {
-			maxTime = d.CreatedAt
-		}
-	}
-	return maxTime
+// isValid reports whether a reading can be aggregated. NaN readings (e.g. from a "nan" fault) are skipped.
+func isValid(v float32) bool {
+	return !math.IsNaN(float64(v))
 }
 
-// calculateAverage returns average values from a slice of SensorData.
-func calculateAverage(data []model.SensorData) model.ResultData {
-	var sumSpeed, sumTemp, sumPressure float32
-	n := float32(len(data))
+// reading extracts one channel from a SensorData.
+type reading func(model.SensorData) float32
+
+func speedOf(d model.SensorData) float32              { return d.Speed }
+func temperatureOf(d model.SensorData) float32        { return d.Temperature }
+func pressureOf(d model.SensorData) float32           { return d.Pressure }
+func batteryVoltageOf(d model.SensorData) float32     { return d.BatteryVoltage }
+func batteryCurrentOf(d model.SensorData) float32     { return d.BatteryCurrent }
+func batterySoCOf(d model.SensorData) float32         { return d.BatterySoC }
+func batteryTemperatureOf(d model.SensorData) float32 { return d.BatteryTemperature }
+
+// channelReadings extracts every channel of a SensorData, in the order of sensorChannels.
+var channelReadings = []reading{
+	speedOf,
+	pressureOf,
+	temperatureOf,
+	batteryVoltageOf,
+	batteryCurrentOf,
+	batterySoCOf,
+	batteryTemperatureOf,
+}
 
-	for _, d := range data {
-		sumSpeed += d.Speed
-		sumTemp += d.Temperature
-		sumPressure += d.Pressure
+// percentile returns the p-th percentile of sorted values, interpolating linearly between the closest ranks.
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	if lo+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
 	}
+	return sorted[lo] + (rank-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
 
-	return model.ResultData{
-		AverageSpeed:       sumSpeed / n,
-		AverageTemperature: sumTemp / n,
-		AveragePressure:    sumPressure / n,
+// percentileName returns the name a percentile is reported under, e.g. "p95" or "p99.9".
+func percentileName(p float64) string {
+	return fmt.Sprintf("p%g", p)
+}
+
+// inRange reports whether v is a finite reading within [min, max]. An unset range (max <= min) only rules out NaN and ±Inf.
+func inRange(v, min, max float32) bool {
+	if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+		return false
 	}
+	return max <= min || (v >= min && v <= max)
 }
 
-// calculateMin returns minimum values from a slice of SensorData.
-func calculateMin(data []model.SensorData) model.ResultData {
-	minSpeed := data[0].Speed
-	minTemp := data[0].Temperature
-	minPressure := data[0].Pressure
+// outOfRange reports whether any channel of d is invalid or outside the limits of its sensor.
+func outOfRange(d model.SensorData, sensor config.SensorConfig) bool {
+	battery := sensor.Battery
+	return !inRange(d.Speed, sensor.MinSpeed, sensor.MaxSpeed) ||
+		!inRange(d.Pressure, sensor.MinPressure, sensor.MaxPressure) ||
+		!inRange(d.Temperature, sensor.MinTemp, sensor.MaxTemp) ||
+		!inRange(d.BatteryVoltage, float32(battery.MinVoltage), float32(battery.MaxVoltage)) ||
+		!inRange(d.BatteryCurrent, -float32(battery.MaxCurrent), float32(battery.MaxCurrent)) ||
+		!inRange(d.BatterySoC, 0, 100) ||
+		!inRange(d.BatteryTemperature, sensor.MinTemp, sensor.MaxTemp)
+}
 
-	for _, d := range data[1:] {
-		if d.Speed < minSpeed {
-			minSpeed = d.Speed
-		}
-		if d.Temperature < minTemp {
-			minTemp = d.Temperature
-		}
-		if d.Pressure < minPressure {
-			minPressure = d.Pressure
-		}
+// expectedReadings returns how many readings the sensor produces in span, or 0 when its interval is unknown.
+func expectedReadings(span time.Duration, sensor config.SensorConfig) float32 {
+	if sensor.Interval <= 0 {
+		return 0
 	}
+	return float32(span.Seconds() / sensor.Interval.Seconds())
+}
 
-	return model.ResultData{
-		MinimumSpeed:       minSpeed,
-		MinimumTemperature: minTemp,
-		MinimumPressure:    minPressure,
-	}
+/*
+batcher groups SensorData into windows of interval by their CreatedAt (event time),
+so the batches only depend on the readings and not on when they were received.
+Windows are aligned to multiples of interval since the zero time.
+*/
+type batcher struct {
+	interval    time.Duration
+	percentiles []float64            // reported for every channel
+	vehicle     config.VehicleConfig // whose sensor interval and limits the batches are assessed against
+	end         time.Time            // end of the open window, or of the last one closed when none is open
+	last        time.Time            // CreatedAt of the latest reading
+	agg         aggregator           // readings of the open window
 }
 
-// calculateMax returns maximum values from a slice of SensorData.
-func calculateMax(data []model.SensorData) model.ResultData {
-	maxSpeed := data[0].Speed
-	maxTemp := data[0].Temperature
-	maxPressure := data[0].Pressure
+// close summarizes the open window, assessed against the readings expected in it.
+func (b *batcher) close() model.ResultData {
+	result := b.agg.result(b.end, b.percentiles, expectedReadings(b.interval, b.vehicle.Sensor))
+	b.agg.reset()
+	return result
+}
 
-	for _, d := range data[1:] {
-		if d.Speed > maxSpeed {
-			maxSpeed = d.Speed
-		}
-		if d.Temperature > maxTemp {
-			maxTemp = d.Temperature
-		}
-		if d.Pressure > maxPressure {
-			maxPressure = d.Pressure
-		}
+/*
+heartbeat is called when no reading arrived for a whole interval, idle being the time since the latest one arrived,
+e.g. while the sensor is stalled or when its interval is longer than the batches. It closes the open window as is or,
+without one, returns a Stale result for the window after the last one, with no statistics and CreatedAt at the latest reading.
+Before the first reading, and until idle exceeds both the sensor interval and the batch interval, there is nothing to report:
+a sensor slower than the batches leaves windows empty without being stalled.
+*/
+func (b *batcher) heartbeat(idle time.Duration) (model.ResultData, bool) {
+	if b.agg.count > 0 {
+		return b.close(), true
+	}
+	if b.end.IsZero() || idle <= max(b.vehicle.Sensor.Interval, b.interval) {
+		return model.ResultData{}, false
 	}
 
-	return model.ResultData{
-		MaximumSpeed:       maxSpeed,
-		MaximumTemperature: maxTemp,
-		MaximumPressure:    maxPressure,
+	b.end = b.end.Add(b.interval)
+	result := b.close()
+	result.VehicleID = b.vehicle.VehicleID
+	result.CreatedAt = b.last
+	return result, true
+}
+
+// add appends data to its window. When data opens a new window, the previous one is closed and returned.
+func (b *batcher) add(data model.SensorData) (model.ResultData, bool) {
+	var result model.ResultData
+	closed := false
+	if b.agg.count > 0 && !data.CreatedAt.Before(b.end) {
+		result, closed = b.close(), true
+	}
+	if b.agg.count == 0 {
+		b.end = data.CreatedAt.Truncate(b.interval).Add(b.interval)
 	}
+	b.agg.add(data, b.vehicle.Sensor)
+	if data.CreatedAt.After(b.last) {
+		b.last = data.CreatedAt
+	}
+	return result, closed
 }
 
 /*
-Process collects SensorData values from the input channel into a slice.
-Every batchInterval, it calculates statistics (average, min, max) using
-separate goroutines (fan-out/fan-in pattern), builds a Result, and sends it to the output channel.
+Process collects SensorData values of vehicle from the input channel into batches of batchInterval,
+by the time each reading was taken. Once a reading falls past the open batch, it calculates
+statistics (average, min, max, the distribution of every channel and the position of the latest reading),
+builds a Result stamped with the end of the batch, and pushes it to the output queue.
 
 Note:
-  - The slice is cleared after each batch, so results are not cumulative.
-  - Calculations are split into separate functions/goroutines for concurrency practice,
-    even though a single-pass calculation would be faster and use less computational overhead.
+  - Statistics are updated incrementally as readings arrive (see aggregator.go), in a single goroutine,
+    and the aggregator is reset after each batch, so results are not cumulative.
+  - NaN readings are left out of the statistics.
+  - Every Result is assessed against the sensor of vehicle: the readings expected from its interval
+    and the readings outside its configured limits.
+  - When no reading arrives for a whole batchInterval of the clock, the open batch is closed as is or,
+    once none arrived for longer than both the sensor interval and batchInterval, a Stale gap Result
+    is pushed for every interval until readings resume.
+  - The same readings always produce the same Results, which keeps seeded runs reproducible.
+  - Every configured window (sliding, hopping, tumbling or count, see window.go) aggregates the same
+    readings alongside the batches, and its Results are tagged with the window name.
+  - The alert rules of config.Alerts are evaluated on every reading, batch and window Result (see alerts.go),
+    and the alarms they raise and clear are pushed as "alert" Events to outEventQueue.
+  - While raw is switched on for vehicle, the readings are also forwarded as they are, in SensorFrames (see raw.go).
 */
-func Process(inChan <-chan model.SensorData, outChan chan<- model.ResultData) {
-	batchInterval := config.Processor.Interval // defines how often results are calculated.
+func Process(vehicle config.VehicleConfig, inChan <-chan model.SensorData, outQueue *queue.Queue[model.ResultData], outEventQueue *queue.Queue[model.Event], raw *rawStream) {
+	b := &batcher{interval: config.Processor.Interval, percentiles: config.Processor.Percentiles, vehicle: vehicle} // defines how often results are calculated.
+	windows := make([]window, 0, len(config.Processor.Windows))
+	for _, cfg := range config.Processor.Windows {
+		windows = append(windows, newWindow(cfg, config.Processor.Percentiles, vehicle.Sensor))
+	}
+	alarms := newAlerting(vehicle.VehicleID, config.Alerts.Rules, func(e model.Event) { outEventQueue.Push(e) })
+	push := func(result model.ResultData) {
+		outQueue.Push(result)
+		alarms.result(result)
+	}
+	frames := &framer{stream: raw}
 
-	var dataSlice []model.SensorData
-	ticker := time.NewTicker(batchInterval)
+	ticker := clock.NewTicker(config.Processor.Interval)
 	defer ticker.Stop()
 
-	log.Println("[INFO][Generator][Process] Running.")
+	log.Printf("[INFO][Generator][Process] %s running.", vehicle.VehicleID)
 
+	arrived, stalled := false, false
+	var lastArrival time.Time // clock time the latest reading arrived at
 	for {
 		select {
-		case data := <-inChan:
-			dataSlice = append(dataSlice, data)
-		case <-ticker.C:
-			// Channels for calculations
-			tmeChan := make(chan time.Time)
-			avgChan := make(chan model.ResultData)
-			minChan := make(chan model.ResultData)
-			maxChan := make(chan model.ResultData)
-
-			// Goroutines for calculations
-			go func() { tmeChan <- getLastTime(dataSlice) }()
-			go func() { avgChan <- calculateAverage(dataSlice) }()
-			go func() { minChan <- calculateMin(dataSlice) }()
-			go func() { maxChan <- calculateMax(dataSlice) }()
-
-			// Wait for results
-			tme := <-tmeChan
-			avg := <-avgChan
-			min := <-minChan
-			max := <-maxChan
-
-			// Build ResultData
-			result := model.ResultData{
-				AverageSpeed:       avg.AverageSpeed,
-				MinimumSpeed:       min.MinimumSpeed,
-				MaximumSpeed:       max.MaximumSpeed,
-				AverageTemperature: avg.AverageTemperature,
-				MinimumTemperature: min.MinimumTemperature,
-				MaximumTemperature: max.MaximumTemperature,
-				AveragePressure:    avg.AveragePressure,
-				MinimumPressure:    min.MinimumPressure,
-				MaximumPressure:    max.MaximumPressure,
-				VehicleID:          dataSlice[0].VehicleID,
-				CreatedAt:          tme,
-				ProcessedAt:        time.Now().Local(),
+		case data, ok := <-inChan:
+			if !ok {
+				return
+			}
+			if stalled {
+				log.Printf("[INFO][Generator][Process] %s readings resumed.", vehicle.VehicleID)
+			}
+			arrived, stalled = true, false
+			lastArrival = clock.Now()
+			alarms.sample(data)
+			frames.add(data)
+
+			if result, ok := b.add(data); ok {
+				push(result)
+			}
+			for _, w := range windows {
+				for _, result := range w.add(data) {
+					push(result)
+				}
 			}
-			outChan <- result
 
-			// Reset slice for next batch
-			dataSlice = []model.SensorData{}
+		case <-ticker.C:
+			if !arrived {
+				frames.flush()
+				if result, ok := b.heartbeat(clock.Since(lastArrival)); ok {
+					if result.Stale && !stalled {
+						log.Printf("[WARN][Generator][Process] %s no readings since %s.", vehicle.VehicleID, result.CreatedAt.Format("15:04:05.000"))
+						stalled = true
+					}
+					push(result)
+				}
+			}
+			arrived = false
 		}
 	}
 }
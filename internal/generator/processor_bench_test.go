@@ -0,0 +1,416 @@
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+/*
+The legacy summary below is how Process aggregated a batch before the aggregator:
+the readings were kept in a slice and every statistic took its own pass over it, in its own goroutine.
+It is kept as the reference the aggregator is checked and benchmarked against.
+*/
+
+// legacyLatest returns the latest SensorData of a slice, by its timestamp.
+func legacyLatest(data []model.SensorData) model.SensorData {
+	latest := data[0]
+	for _, d := range data[1:] {
+		if d.CreatedAt.After(latest.CreatedAt) {
+			latest = d
+		}
+	}
+	return latest
+}
+
+// legacyFinite replaces the ±Inf left by a channel without valid readings with 0.
+func legacyFinite(v float32) float32 {
+	if math.IsInf(float64(v), 0) {
+		return 0
+	}
+	return v
+}
+
+// legacyAverageOf returns the average of the valid readings of a channel, or 0 when there are none.
+func legacyAverageOf(data []model.SensorData, get reading) float32 {
+	var sum float32
+	n := 0
+	for _, d := range data {
+		if v := get(d); isValid(v) {
+			sum += v
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float32(n)
+}
+
+// legacyMinOf returns the minimum valid reading of a channel, or 0 when there are none.
+func legacyMinOf(data []model.SensorData, get reading) float32 {
+	min := float32(math.Inf(1))
+	for _, d := range data {
+		if v := get(d); isValid(v) && v < min {
+			min = v
+		}
+	}
+	return legacyFinite(min)
+}
+
+// legacyMaxOf returns the maximum valid reading of a channel, or 0 when there are none.
+func legacyMaxOf(data []model.SensorData, get reading) float32 {
+	max := float32(math.Inf(-1))
+	for _, d := range data {
+		if v := get(d); isValid(v) && v > max {
+			max = v
+		}
+	}
+	return legacyFinite(max)
+}
+
+/*
+legacyStatsOf returns the distribution of the valid readings of a channel: their count, sample standard deviation,
+median and percentiles, the first and last reading by time and the rate of change between them.
+*/
+func legacyStatsOf(data []model.SensorData, get reading, percentiles []float64) model.ChannelStats {
+	values := make([]float64, 0, len(data))
+	var first, last model.SensorData
+	var sum float64
+	for _, d := range data {
+		v := get(d)
+		if !isValid(v) {
+			continue
+		}
+		if len(values) == 0 || d.CreatedAt.Before(first.CreatedAt) {
+			first = d
+		}
+		if len(values) == 0 || !d.CreatedAt.Before(last.CreatedAt) {
+			last = d
+		}
+		values = append(values, float64(v))
+		sum += float64(v)
+	}
+
+	stats := model.ChannelStats{Count: len(values)}
+	if len(values) == 0 {
+		return stats
+	}
+
+	stats.First, stats.Last = get(first), get(last)
+	if span := last.CreatedAt.Sub(first.CreatedAt).Seconds(); span > 0 {
+		stats.Rate = float32((float64(stats.Last) - float64(stats.First)) / span)
+	}
+
+	if len(values) > 1 {
+		mean := sum / float64(len(values))
+		var squares float64
+		for _, v := range values {
+			squares += (v - mean) * (v - mean)
+		}
+		stats.StdDev = float32(math.Sqrt(squares / float64(len(values)-1)))
+	}
+
+	sort.Float64s(values)
+	stats.Median = float32(percentile(values, 50))
+	if len(percentiles) > 0 {
+		stats.Percentiles = make(map[string]float32, len(percentiles))
+		for _, p := range percentiles {
+			stats.Percentiles[percentileName(p)] = float32(percentile(values, p))
+		}
+	}
+	return stats
+}
+
+// legacyStats returns the distribution of every channel of a slice of SensorData, by channel name.
+func legacyStats(data []model.SensorData, percentiles []float64) map[string]model.ChannelStats {
+	stats := make(map[string]model.ChannelStats, len(channelReadings))
+	for i, get := range channelReadings {
+		stats[sensorChannels[i]] = legacyStatsOf(data, get, percentiles)
+	}
+	return stats
+}
+
+// legacyAverage returns average values from a slice of SensorData.
+func legacyAverage(data []model.SensorData) model.ResultData {
+	return model.ResultData{
+		AverageSpeed:              legacyAverageOf(data, speedOf),
+		AverageTemperature:        legacyAverageOf(data, temperatureOf),
+		AveragePressure:           legacyAverageOf(data, pressureOf),
+		AverageBatteryVoltage:     legacyAverageOf(data, batteryVoltageOf),
+		AverageBatteryCurrent:     legacyAverageOf(data, batteryCurrentOf),
+		AverageBatterySoC:         legacyAverageOf(data, batterySoCOf),
+		AverageBatteryTemperature: legacyAverageOf(data, batteryTemperatureOf),
+	}
+}
+
+// legacyMin returns minimum values from a slice of SensorData.
+func legacyMin(data []model.SensorData) model.ResultData {
+	return model.ResultData{
+		MinimumSpeed:              legacyMinOf(data, speedOf),
+		MinimumTemperature:        legacyMinOf(data, temperatureOf),
+		MinimumPressure:           legacyMinOf(data, pressureOf),
+		MinimumBatteryVoltage:     legacyMinOf(data, batteryVoltageOf),
+		MinimumBatteryCurrent:     legacyMinOf(data, batteryCurrentOf),
+		MinimumBatterySoC:         legacyMinOf(data, batterySoCOf),
+		MinimumBatteryTemperature: legacyMinOf(data, batteryTemperatureOf),
+	}
+}
+
+// legacyMax returns maximum values from a slice of SensorData.
+func legacyMax(data []model.SensorData) model.ResultData {
+	return model.ResultData{
+		MaximumSpeed:              legacyMaxOf(data, speedOf),
+		MaximumTemperature:        legacyMaxOf(data, temperatureOf),
+		MaximumPressure:           legacyMaxOf(data, pressureOf),
+		MaximumBatteryVoltage:     legacyMaxOf(data, batteryVoltageOf),
+		MaximumBatteryCurrent:     legacyMaxOf(data, batteryCurrentOf),
+		MaximumBatterySoC:         legacyMaxOf(data, batterySoCOf),
+		MaximumBatteryTemperature: legacyMaxOf(data, batteryTemperatureOf),
+	}
+}
+
+// legacyAssess fills in the data-quality fields of result, the summary of data, given the readings expected.
+func legacyAssess(result *model.ResultData, data []model.SensorData, sensor config.SensorConfig, expected float32) {
+	result.ExpectedCount = expected
+	result.OutOfRangeCount = 0
+	for _, d := range data {
+		if outOfRange(d, sensor) {
+			result.OutOfRangeCount++
+		}
+	}
+}
+
+/*
+legacySummarize calculates the statistics of a batch of SensorData closed at processedAt, reporting the given percentiles.
+An empty batch has no statistics: it returns a Stale result, which the caller stamps with its vehicle.
+*/
+func legacySummarize(dataSlice []model.SensorData, processedAt time.Time, percentiles []float64) model.ResultData {
+	if len(dataSlice) == 0 {
+		return model.ResultData{Stale: true, ProcessedAt: processedAt}
+	}
+
+	// Channels for calculations
+	lstChan := make(chan model.SensorData)
+	avgChan := make(chan model.ResultData)
+	minChan := make(chan model.ResultData)
+	maxChan := make(chan model.ResultData)
+	stsChan := make(chan map[string]model.ChannelStats)
+
+	// Goroutines for calculations
+	go func() { lstChan <- legacyLatest(dataSlice) }()
+	go func() { avgChan <- legacyAverage(dataSlice) }()
+	go func() { minChan <- legacyMin(dataSlice) }()
+	go func() { maxChan <- legacyMax(dataSlice) }()
+	go func() { stsChan <- legacyStats(dataSlice, percentiles) }()
+
+	// Wait for results
+	lst := <-lstChan
+	avg := <-avgChan
+	min := <-minChan
+	max := <-maxChan
+	sts := <-stsChan
+
+	// Build ResultData
+	return model.ResultData{
+		AverageSpeed:              avg.AverageSpeed,
+		MinimumSpeed:              min.MinimumSpeed,
+		MaximumSpeed:              max.MaximumSpeed,
+		AverageTemperature:        avg.AverageTemperature,
+		MinimumTemperature:        min.MinimumTemperature,
+		MaximumTemperature:        max.MaximumTemperature,
+		AveragePressure:           avg.AveragePressure,
+		MinimumPressure:           min.MinimumPressure,
+		MaximumPressure:           max.MaximumPressure,
+		AverageBatteryVoltage:     avg.AverageBatteryVoltage,
+		MinimumBatteryVoltage:     min.MinimumBatteryVoltage,
+		MaximumBatteryVoltage:     max.MaximumBatteryVoltage,
+		AverageBatteryCurrent:     avg.AverageBatteryCurrent,
+		MinimumBatteryCurrent:     min.MinimumBatteryCurrent,
+		MaximumBatteryCurrent:     max.MaximumBatteryCurrent,
+		AverageBatterySoC:         avg.AverageBatterySoC,
+		MinimumBatterySoC:         min.MinimumBatterySoC,
+		MaximumBatterySoC:         max.MaximumBatterySoC,
+		AverageBatteryTemperature: avg.AverageBatteryTemperature,
+		MinimumBatteryTemperature: min.MinimumBatteryTemperature,
+		MaximumBatteryTemperature: max.MaximumBatteryTemperature,
+		Position:                  lst.Position,
+		DistanceToEnd:             lst.DistanceToEnd,
+		Count:                     len(dataSlice),
+		Stats:                     sts,
+		VehicleID:                 dataSlice[0].VehicleID,
+		CreatedAt:                 lst.CreatedAt,
+		ProcessedAt:               processedAt,
+	}
+}
+
+// benchReadings returns n readings of goldenVehicle, 10ms apart, with a NaN and an out-of-range reading now and then.
+func benchReadings(n int) []model.SensorData {
+	rng := rand.New(rand.NewSource(1))
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := make([]model.SensorData, n)
+	for i := range data {
+		data[i] = model.SensorData{
+			VehicleID:          goldenVehicle.VehicleID,
+			Speed:              float32(60 + 20*rng.Float64()),
+			Pressure:           float32(2 + rng.Float64()),
+			Temperature:        float32(30 + 5*rng.Float64()),
+			BatteryVoltage:     float32(350 + 20*rng.Float64()),
+			BatteryCurrent:     float32(40 * rng.Float64()),
+			BatterySoC:         float32(90 + rng.Float64()),
+			BatteryTemperature: float32(25 + 2*rng.Float64()),
+			Position:           float32(i),
+			CreatedAt:          start.Add(time.Duration(i) * 10 * time.Millisecond),
+		}
+		switch {
+		case i%17 == 16:
+			data[i].Temperature = float32(math.NaN())
+		case i%23 == 22:
+			data[i].Speed = 500
+		}
+	}
+	return data
+}
+
+// near reports whether two results agree, up to the rounding of the averages and deviations.
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) <= 1e-4*math.Max(1, math.Abs(float64(b)))
+}
+
+// rankOf returns the percentage of the valid readings of a channel at most v.
+func rankOf(data []model.SensorData, get reading, v float32) float64 {
+	var below, valid int
+	for _, d := range data {
+		if r := get(d); isValid(r) {
+			valid++
+			if r <= v {
+				below++
+			}
+		}
+	}
+	return 100 * float64(below) / float64(valid)
+}
+
+func TestAggregatorMatchesLegacy(t *testing.T) {
+	percentiles := []float64{95, 99}
+	sensor := goldenVehicle.Sensor
+	var agg aggregator
+	for _, n := range []int{1, 2, 10, 100, 1000} {
+		data := benchReadings(n)
+		end := data[n-1].CreatedAt.Add(10 * time.Millisecond)
+
+		want := legacySummarize(data, end, percentiles)
+		legacyAssess(&want, data, sensor, 4)
+		agg.reset()
+		for _, d := range data {
+			agg.add(d, sensor)
+		}
+		got := agg.result(end, percentiles, 4)
+
+		averages := [][2]float32{
+			{got.AverageSpeed, want.AverageSpeed}, {got.AverageTemperature, want.AverageTemperature},
+			{got.AveragePressure, want.AveragePressure}, {got.AverageBatteryVoltage, want.AverageBatteryVoltage},
+			{got.AverageBatteryCurrent, want.AverageBatteryCurrent}, {got.AverageBatterySoC, want.AverageBatterySoC},
+			{got.AverageBatteryTemperature, want.AverageBatteryTemperature},
+		}
+		for i, avg := range averages {
+			if !near(avg[0], avg[1]) {
+				t.Errorf("%d readings: average %d = %v, want %v", n, i, avg[0], avg[1])
+			}
+		}
+
+		// Everything else is exact, once the averages and deviations are set aside
+		gotStats, wantStats := got.Stats, want.Stats
+		got.Stats, want.Stats = nil, nil
+		got.AverageSpeed, got.AverageTemperature, got.AveragePressure = want.AverageSpeed, want.AverageTemperature, want.AveragePressure
+		got.AverageBatteryVoltage, got.AverageBatteryCurrent = want.AverageBatteryVoltage, want.AverageBatteryCurrent
+		got.AverageBatterySoC, got.AverageBatteryTemperature = want.AverageBatterySoC, want.AverageBatteryTemperature
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%d readings: result = %+v, want %+v", n, got, want)
+		}
+		for name, w := range wantStats {
+			g := gotStats[name]
+			if !near(g.StdDev, w.StdDev) || !near(g.Rate, w.Rate) {
+				t.Errorf("%d readings: %s stddev, rate = %v, %v, want %v, %v", n, name, g.StdDev, g.Rate, w.StdDev, w.Rate)
+			}
+
+			// The quantiles are exact up to quantileSamples readings, and estimated from a sample of them beyond:
+			// then the estimate must rank within 5% of the quantile among the readings
+			quantiles := map[float64][2]float32{50: {g.Median, w.Median}}
+			for _, p := range percentiles {
+				quantiles[p] = [2]float32{g.Percentiles[percentileName(p)], w.Percentiles[percentileName(p)]}
+			}
+			for q, v := range quantiles {
+				if n <= quantileSamples && v[0] != v[1] {
+					t.Errorf("%d readings: %s p%g = %v, want %v", n, name, q, v[0], v[1])
+				}
+				if rank := rankOf(data, channelReadings[channelIndex(name)], v[0]); n > quantileSamples && math.Abs(rank-q) > 5 {
+					t.Errorf("%d readings: %s p%g = %v, ranked at %.1f%%", n, name, q, v[0], rank)
+				}
+			}
+			g.StdDev, g.Rate, w.StdDev, w.Rate = 0, 0, 0, 0
+			g.Median, w.Median = 0, 0
+			g.Percentiles, w.Percentiles = nil, nil
+			if !reflect.DeepEqual(g, w) {
+				t.Errorf("%d readings: %s = %+v, want %+v", n, name, g, w)
+			}
+		}
+	}
+}
+
+/*
+The benchmarks summarize batches of 10, 100, 1000 and 10000 readings, from the first reading to the result,
+as the batcher does: the legacy summary collects them in a fresh slice, the aggregator reuses its buffers
+and keeps at most quantileSamples readings per channel, so its cost per reading stays flat on large batches.
+Compare them with: go test ./internal/generator -run '^$' -bench Summarize -benchmem
+*/
+
+func BenchmarkSummarizeLegacy(b *testing.B) {
+	percentiles := []float64{95, 99}
+	for _, n := range []int{10, 100, 1000, 10000} {
+		readings := benchReadings(n)
+		end := readings[n-1].CreatedAt
+		b.Run(sizeName(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				data := []model.SensorData{}
+				for _, d := range readings {
+					data = append(data, d)
+				}
+				result := legacySummarize(data, end, percentiles)
+				legacyAssess(&result, data, goldenVehicle.Sensor, float32(n))
+			}
+		})
+	}
+}
+
+func BenchmarkSummarizeAggregator(b *testing.B) {
+	percentiles := []float64{95, 99}
+	for _, n := range []int{10, 100, 1000, 10000} {
+		readings := benchReadings(n)
+		end := readings[n-1].CreatedAt
+		b.Run(sizeName(n), func(b *testing.B) {
+			var agg aggregator
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, d := range readings {
+					agg.add(d, goldenVehicle.Sensor)
+				}
+				agg.result(end, percentiles, float32(n))
+				agg.reset()
+			}
+		})
+	}
+}
+
+// sizeName names a sub-benchmark after the readings of its batch.
+func sizeName(n int) string {
+	return strconv.Itoa(n) + "-readings"
+}
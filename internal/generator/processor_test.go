@@ -0,0 +1,137 @@
+package generator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+func TestChannelAggregate(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	speeds := []float32{4, 1, float32(math.NaN()), 3, 2, 5}
+	var c channelAggregate
+	for i, v := range speeds {
+		c.add(v, start.Add(time.Duration(i)*100*time.Millisecond))
+	}
+
+	if c.mean != 3 || c.min != 1 || c.max != 5 {
+		t.Errorf("mean, min, max = %v, %v, %v, want 3, 1, 5", c.mean, c.min, c.max)
+	}
+	got := c.stats([]float64{95, 99.9})
+	want := model.ChannelStats{
+		Count:       5,
+		StdDev:      float32(math.Sqrt(2.5)),
+		Median:      3,
+		Percentiles: map[string]float32{"p95": 4.8, "p99.9": 4.996},
+		First:       4,
+		Last:        5,
+		Rate:        2, // from 4 to 5 in 0.5s
+	}
+
+	if got.Count != want.Count || !near(got.StdDev, want.StdDev) || !near(got.Median, want.Median) ||
+		got.First != want.First || got.Last != want.Last || !near(got.Rate, want.Rate) {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+	for name, v := range want.Percentiles {
+		if !near(got.Percentiles[name], v) {
+			t.Errorf("%s = %v, want %v", name, got.Percentiles[name], v)
+		}
+	}
+
+	// Large batches keep a bounded sample of their readings, still estimating the quantiles
+	c.reset()
+	for i := 0; i < 20*quantileSamples; i++ {
+		c.add(float32(i%1000), start.Add(time.Duration(i)*time.Millisecond))
+	}
+	large := c.stats([]float64{95})
+	if len(c.values) != quantileSamples || math.Abs(float64(large.Median)-500) > 50 || math.Abs(float64(large.Percentiles["p95"])-950) > 25 {
+		t.Errorf("kept %d readings, median %v, p95 %v, want %d, about 500 and 950", len(c.values), large.Median, large.Percentiles["p95"], quantileSamples)
+	}
+
+	c.reset()
+	c.add(float32(math.NaN()), start)
+	if empty := c.stats([]float64{95}); empty.Count != 0 || empty.Percentiles != nil {
+		t.Errorf("stats of NaN readings = %+v, want no statistics", empty)
+	}
+}
+
+func TestBatcherQualityAndHeartbeat(t *testing.T) {
+	vehicle := goldenVehicle
+	vehicle.Sensor.Interval = 25 * time.Millisecond
+	b := &batcher{interval: 100 * time.Millisecond, vehicle: vehicle}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// No reading yet, nothing to report
+	if _, ok := b.heartbeat(time.Second); ok {
+		t.Error("heartbeat before the first reading")
+	}
+
+	// Two of the four expected readings, one of them above maxSpeed
+	b.add(model.SensorData{Speed: 10, BatteryVoltage: 390, BatterySoC: 90, CreatedAt: start})
+	b.add(model.SensorData{Speed: 500, BatteryVoltage: 390, BatterySoC: 90, CreatedAt: start.Add(25 * time.Millisecond)})
+
+	result, ok := b.heartbeat(100 * time.Millisecond)
+	if !ok || result.Stale || result.Count != 2 || result.ExpectedCount != 4 || result.OutOfRangeCount != 1 {
+		t.Errorf("heartbeat with an open batch = %+v, want it closed with 2 of 4 readings, 1 out of range", result)
+	}
+
+	// Not yet stalled: the sensor interval or the batch has not elapsed without readings
+	if result, ok := b.heartbeat(100 * time.Millisecond); ok {
+		t.Errorf("heartbeat after one empty batch = %+v, want nothing yet", result)
+	}
+
+	// The sensor stalls: a gap for every following window
+	for i, end := range []time.Duration{200, 300} {
+		result, ok := b.heartbeat(time.Duration(i+2) * 100 * time.Millisecond)
+		if !ok || !result.Stale || result.Count != 0 || result.VehicleID != vehicle.VehicleID ||
+			!result.ProcessedAt.Equal(start.Add(end*time.Millisecond)) || !result.CreatedAt.Equal(start.Add(25*time.Millisecond)) {
+			t.Errorf("gap %d = %+v, want a stale result for the window ending at %dms", i, result, end)
+		}
+	}
+
+	// Readings resume in a fresh batch
+	b.add(model.SensorData{Speed: 10, BatteryVoltage: 390, BatterySoC: 90, CreatedAt: start.Add(520 * time.Millisecond)})
+	if result, ok := b.add(model.SensorData{Speed: 10, BatteryVoltage: 390, BatterySoC: 90, CreatedAt: start.Add(600 * time.Millisecond)}); !ok ||
+		result.Stale || result.Count != 1 || !result.ProcessedAt.Equal(start.Add(600*time.Millisecond)) {
+		t.Errorf("batch after the gap = %+v, want 1 reading up to 600ms", result)
+	}
+}
+
+func TestBatcherSlowSensorIsNotStale(t *testing.T) {
+	// A sensor reading every 250 ms into batches of 100 ms leaves most batches empty
+	vehicle := goldenVehicle
+	vehicle.Sensor.Interval = 250 * time.Millisecond
+	b := &batcher{interval: 100 * time.Millisecond, vehicle: vehicle}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Process calls heartbeat on every tick of 100 ms in which no reading arrived
+	var results []model.ResultData
+	for tick := 0; tick < 20; tick++ {
+		now := time.Duration(tick) * 100 * time.Millisecond
+		if now%(250*time.Millisecond) < 100*time.Millisecond {
+			arrival := now - now%(250*time.Millisecond)
+			b.add(model.SensorData{Speed: 10, BatteryVoltage: 390, BatterySoC: 90, CreatedAt: start.Add(arrival)})
+			continue
+		}
+		idle := now % (250 * time.Millisecond)
+		if result, ok := b.heartbeat(idle); ok {
+			results = append(results, result)
+		}
+	}
+	for _, result := range results {
+		if result.Stale || result.Count != 1 {
+			t.Fatalf("result = %+v, want batches of one reading and no gaps", result)
+		}
+	}
+	if len(results) != 8 {
+		t.Errorf("%d batches, want one per reading", len(results))
+	}
+
+	// Once it stops for longer than its interval, the empty windows are gaps
+	result, ok := b.heartbeat(300 * time.Millisecond)
+	if !ok || !result.Stale {
+		t.Errorf("heartbeat 300 ms after the last reading = %+v, want a gap", result)
+	}
+}
@@ -0,0 +1,111 @@
+package generator
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
+)
+
+/*
+rawStream forwards the raw SensorData of the vehicles it is switched on for, in SensorFrames, to out.
+Every vehicle starts as config.Raw.Enabled; the "raw" Command switches vehicles at runtime (see handle).
+A frame holds the readings of up to frame of data time, or maxSamples readings, whichever comes first.
+*/
+type rawStream struct {
+	frame      time.Duration
+	maxSamples int
+	out        *queue.Queue[model.SensorFrame]
+
+	mu       sync.RWMutex
+	all      bool            // for vehicles without a switch of their own
+	vehicles map[string]bool // switched by vehicle since the last switch of every vehicle
+}
+
+func newRawStream(cfg config.RawConfig, out *queue.Queue[model.SensorFrame]) *rawStream {
+	return &rawStream{frame: cfg.Frame, maxSamples: cfg.MaxSamples, out: out, all: cfg.Enabled, vehicles: make(map[string]bool)}
+}
+
+// on reports whether the readings of vehicleID are streamed.
+func (s *rawStream) on(vehicleID string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if b, ok := s.vehicles[vehicleID]; ok {
+		return b
+	}
+	return s.all
+}
+
+/*
+handle applies a "raw" Command and reports whether cmd was one:
+- "Raw b" → streams the raw readings of the vehicle of the Command, or of every vehicle without one
+  (b: true|false, omitted toggles).
+*/
+func (s *rawStream) handle(cmd model.Command) bool {
+	if s == nil || !strings.EqualFold(cmd.Action, "raw") {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cmd.VehicleID == "" {
+		b, ok := cmd.Params.(bool)
+		if !ok {
+			b = !s.all
+		}
+		s.all, s.vehicles = b, make(map[string]bool)
+		log.Printf("[INFO][Generator][Raw] Raw stream: %t.", b)
+		return true
+	}
+
+	current, set := s.vehicles[cmd.VehicleID]
+	if !set {
+		current = s.all
+	}
+	b, ok := cmd.Params.(bool)
+	if !ok {
+		b = !current
+	}
+	s.vehicles[cmd.VehicleID] = b
+	log.Printf("[INFO][Generator][Raw] %s raw stream: %t.", cmd.VehicleID, b)
+	return true
+}
+
+// framer collects the readings of a vehicle into the SensorFrames of a rawStream.
+type framer struct {
+	stream *rawStream
+	frame  model.SensorFrame
+}
+
+// add takes the next reading, pushing the frame it closes. Readings of a vehicle switched off are dropped.
+func (f *framer) add(d model.SensorData) {
+	if !f.stream.on(d.VehicleID) {
+		f.flush()
+		return
+	}
+
+	if n := len(f.frame.Samples); n > 0 && (n >= f.stream.maxSamples || d.CreatedAt.Sub(f.frame.StartedAt) >= f.stream.frame) {
+		f.flush()
+	}
+	if len(f.frame.Samples) == 0 {
+		f.frame = model.SensorFrame{VehicleID: d.VehicleID, StartedAt: d.CreatedAt}
+	}
+	f.frame.Samples = append(f.frame.Samples, d)
+	f.frame.CreatedAt = d.CreatedAt
+}
+
+// flush pushes the open frame, if any, e.g. once the readings stop.
+func (f *framer) flush() {
+	if len(f.frame.Samples) == 0 {
+		return
+	}
+	f.stream.out.Push(f.frame)
+	f.frame = model.SensorFrame{}
+}
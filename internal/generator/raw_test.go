@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
+)
+
+func TestRawStreamFrames(t *testing.T) {
+	defer func(channels map[string]config.ChannelConfig) { config.Pipeline.Channels = channels }(config.Pipeline.Channels)
+	config.Pipeline.Channels = map[string]config.ChannelConfig{"test.raw": {Capacity: 16, Overflow: config.OverflowBlock}}
+	out := queue.New[model.SensorFrame]("test.raw")
+	stream := newRawStream(config.RawConfig{Frame: 10 * time.Millisecond, MaxSamples: 4}, out)
+	frames := &framer{stream: stream}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := func(vehicleID string, from, to int) {
+		for i := from; i < to; i++ {
+			frames.add(model.SensorData{VehicleID: vehicleID, Speed: float32(i), CreatedAt: start.Add(time.Duration(i) * 3 * time.Millisecond)})
+		}
+	}
+	received := func() []model.SensorFrame {
+		var got []model.SensorFrame
+		for {
+			select {
+			case f := <-out.Out():
+				got = append(got, f)
+			default:
+				return got
+			}
+		}
+	}
+
+	// Off by default
+	feed("123", 0, 10)
+	frames.flush()
+	if got := received(); len(got) != 0 {
+		t.Fatalf("frames while off = %d, want none", len(got))
+	}
+
+	// Every 3 ms over 10 ms frames: 4 readings each, then the rest once flushed
+	stream.handle(model.Command{Action: "raw"})
+	feed("123", 10, 20)
+	frames.flush()
+	got := received()
+	if len(got) != 3 || len(got[0].Samples) != 4 || len(got[1].Samples) != 4 || len(got[2].Samples) != 2 {
+		t.Fatalf("frames = %+v, want 4, 4 and 2 readings", got)
+	}
+	if got[0].VehicleID != "123" || !got[0].StartedAt.Equal(start.Add(30*time.Millisecond)) || !got[0].CreatedAt.Equal(start.Add(39*time.Millisecond)) {
+		t.Errorf("first frame = %+v, want vehicle 123 from 30 ms to 39 ms", got[0])
+	}
+
+	// Switching a vehicle off keeps the others on, and flushes its open frame
+	stream.handle(model.Command{Action: "raw", VehicleID: "123", Params: false})
+	if stream.on("123") || !stream.on("456") {
+		t.Fatalf("on(123) = %t, on(456) = %t after switching 123 off", stream.on("123"), stream.on("456"))
+	}
+	stream.handle(model.Command{Action: "raw", VehicleID: "123"})
+	feed("123", 20, 22)
+	stream.handle(model.Command{Action: "raw", VehicleID: "123"})
+	feed("123", 22, 24)
+	if got := received(); len(got) != 1 || len(got[0].Samples) != 2 {
+		t.Fatalf("frames after toggling off = %+v, want the 2 readings taken while on", got)
+	}
+	if stream.handle(model.Command{Action: "start"}) {
+		t.Error(`handle("start") = true, want it left to the vehicles`)
+	}
+
+	// NaN readings survive the JSON round trip as null
+	frame := model.SensorFrame{VehicleID: "123", Samples: []model.SensorData{{Speed: float32(math.NaN()), Pressure: 2, CreatedAt: start}}}
+	data, err := json.Marshal(frame)
+	if err != nil {
+		t.Fatalf("marshalling a frame with NaN: %v", err)
+	}
+	var decoded model.SensorFrame
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshalling %s: %v", data, err)
+	}
+	if s := decoded.Samples[0]; !math.IsNaN(float64(s.Speed)) || s.Pressure != 2 || s.VehicleID != "123" || !s.CreatedAt.Equal(start) {
+		t.Errorf("decoded reading = %+v, want NaN speed, pressure 2 and vehicle 123", s)
+	}
+}
@@ -0,0 +1,330 @@
+package generator
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+// record is one recorded reading, either a SensorData or a ResultData.
+type record struct {
+	at     time.Time
+	sensor *model.SensorData
+	result *model.ResultData
+}
+
+/*
+loadRecording reads the records of path, a file or a directory whose files are read in name order.
+Supported files:
+- .jsonl / .json: one JSON SensorData or ResultData per line, like the file sink writes.
+  Lines holding another JSON object (Commands, Events) are skipped.
+- consumer data logs: "[DATA] ..." lines as written under logs/data/, read as ResultData.
+- .csv: a header naming SensorData or ResultData fields (case-insensitive) and one reading per row.
+Records keep their file order, since recordings are written as they happen.
+*/
+func loadRecording(path string) ([]record, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			ext := strings.ToLower(filepath.Ext(e.Name()))
+			if !e.IsDir() && (ext == ".jsonl" || ext == ".json" || ext == ".csv") {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+		sort.Strings(files)
+	}
+
+	var records []record
+	for _, file := range files {
+		r, err := loadRecordingFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		records = append(records, r...)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no SensorData or ResultData found in %s", path)
+	}
+	return records, nil
+}
+
+func loadRecordingFile(path string) ([]record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return readCSV(f)
+	}
+	return readLines(f, logDate(path))
+}
+
+// readLines reads JSON lines and consumer "[DATA]" log lines. date is the day of the log, whose lines only hold a time of day.
+func readLines(r io.Reader, date time.Time) ([]record, error) {
+	var records []record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "{"):
+			rec, ok, err := parseJSONRecord([]byte(line))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			if ok {
+				records = append(records, rec)
+			}
+		case strings.HasPrefix(line, "[DATA]"):
+			rec, err := parseDataLogLine(line, date)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			records = append(records, rec)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// parseJSONRecord decodes a SensorData or a ResultData, and reports false for any other object.
+func parseJSONRecord(line []byte) (record, bool, error) {
+	var probe struct {
+		Speed        *float32
+		AverageSpeed *float32
+	}
+	if err := json.Unmarshal(line, &probe); err != nil {
+		return record{}, false, err
+	}
+
+	switch {
+	case probe.AverageSpeed != nil:
+		var res model.ResultData
+		if err := json.Unmarshal(line, &res); err != nil {
+			return record{}, false, err
+		}
+		return record{at: res.CreatedAt, result: &res}, true, nil
+	case probe.Speed != nil:
+		var data model.SensorData
+		if err := json.Unmarshal(line, &data); err != nil {
+			return record{}, false, err
+		}
+		return record{at: data.CreatedAt, sensor: &data}, true, nil
+	}
+	return record{}, false, nil
+}
+
+// logDate returns the day encoded in a consumer log name such as data_20251019_114139.jsonl, or today.
+func logDate(path string) time.Time {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if i := strings.IndexByte(name, '_'); i >= 0 {
+		if t, err := time.ParseInLocation("20060102_150405", name[i+1:], time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Now()
+}
+
+// resultFields maps the names used in logs and CSV headers to the fields of a ResultData.
+func resultFields(r *model.ResultData) map[string]*float32 {
+	return map[string]*float32{
+		"avgspeed": &r.AverageSpeed, "minspeed": &r.MinimumSpeed, "maxspeed": &r.MaximumSpeed,
+		"avgtemp": &r.AverageTemperature, "mintemp": &r.MinimumTemperature, "maxtemp": &r.MaximumTemperature,
+		"avgpressure": &r.AveragePressure, "minpressure": &r.MinimumPressure, "maxpressure": &r.MaximumPressure,
+		"avgvoltage": &r.AverageBatteryVoltage, "minvoltage": &r.MinimumBatteryVoltage, "maxvoltage": &r.MaximumBatteryVoltage,
+		"avgcurrent": &r.AverageBatteryCurrent, "mincurrent": &r.MinimumBatteryCurrent, "maxcurrent": &r.MaximumBatteryCurrent,
+		"avgsoc": &r.AverageBatterySoC, "minsoc": &r.MinimumBatterySoC, "maxsoc": &r.MaximumBatterySoC,
+		"avgbatttemp": &r.AverageBatteryTemperature, "minbatttemp": &r.MinimumBatteryTemperature, "maxbatttemp": &r.MaximumBatteryTemperature,
+		"position": &r.Position, "distancetoend": &r.DistanceToEnd, "expected": &r.ExpectedCount,
+
+		"averagespeed": &r.AverageSpeed, "minimumspeed": &r.MinimumSpeed, "maximumspeed": &r.MaximumSpeed,
+		"averagetemperature": &r.AverageTemperature, "minimumtemperature": &r.MinimumTemperature, "maximumtemperature": &r.MaximumTemperature,
+		"averagepressure": &r.AveragePressure, "minimumpressure": &r.MinimumPressure, "maximumpressure": &r.MaximumPressure,
+		"averagebatteryvoltage": &r.AverageBatteryVoltage, "minimumbatteryvoltage": &r.MinimumBatteryVoltage, "maximumbatteryvoltage": &r.MaximumBatteryVoltage,
+		"averagebatterycurrent": &r.AverageBatteryCurrent, "minimumbatterycurrent": &r.MinimumBatteryCurrent, "maximumbatterycurrent": &r.MaximumBatteryCurrent,
+		"averagebatterysoc": &r.AverageBatterySoC, "minimumbatterysoc": &r.MinimumBatterySoC, "maximumbatterysoc": &r.MaximumBatterySoC,
+		"averagebatterytemperature": &r.AverageBatteryTemperature, "minimumbatterytemperature": &r.MinimumBatteryTemperature, "maximumbatterytemperature": &r.MaximumBatteryTemperature,
+	}
+}
+
+// sensorFields maps the names used in CSV headers to the fields of a SensorData.
+func sensorFields(d *model.SensorData) map[string]*float32 {
+	return map[string]*float32{
+		"speed": &d.Speed, "pressure": &d.Pressure, "temperature": &d.Temperature,
+		"batteryvoltage": &d.BatteryVoltage, "batterycurrent": &d.BatteryCurrent,
+		"batterysoc": &d.BatterySoC, "batterytemperature": &d.BatteryTemperature,
+		"position": &d.Position, "distancetoend": &d.DistanceToEnd,
+	}
+}
+
+/*
+parseDataLogLine reads a consumer data log line, e.g.
+"[DATA] Vehicle: 123 | Created at 11:41:49.222930, Processed at ... | AvgSpeed: 59.72, MinSpeed: 59.49, ... | Stats: {...}".
+*/
+func parseDataLogLine(line string, date time.Time) (record, error) {
+	var res model.ResultData
+	fields := resultFields(&res)
+
+	// The channel statistics close the line as JSON
+	line, stats, ok := strings.Cut(line, " | Stats: ")
+	if ok {
+		if err := json.Unmarshal([]byte(stats), &res.Stats); err != nil {
+			return record{}, fmt.Errorf("stats: %w", err)
+		}
+	}
+
+	for _, segment := range strings.Split(strings.TrimPrefix(line, "[DATA]"), "|") {
+		for _, item := range strings.Split(segment, ",") {
+			item = strings.TrimSpace(item)
+			if at, ok := strings.CutPrefix(item, "Created at "); ok {
+				t, err := time.ParseInLocation("15:04:05.000000", at, date.Location())
+				if err != nil {
+					return record{}, err
+				}
+				res.CreatedAt = time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), date.Location())
+				continue
+			}
+
+			key, value, ok := strings.Cut(item, ":")
+			if !ok {
+				continue
+			}
+			key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+			if key == "vehicle" {
+				res.VehicleID = value
+				continue
+			}
+			if key == "window" {
+				res.Window = value
+				continue
+			}
+			if key == "trip" {
+				res.TripID = value
+				continue
+			}
+			if key == "samples" || key == "outofrange" {
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return record{}, fmt.Errorf("%s: %w", key, err)
+				}
+				if key == "samples" {
+					res.Count = n
+				} else {
+					res.OutOfRangeCount = n
+				}
+				continue
+			}
+			if field, ok := fields[key]; ok {
+				v, err := strconv.ParseFloat(value, 32)
+				if err != nil {
+					return record{}, fmt.Errorf("%s: %w", key, err)
+				}
+				*field = float32(v)
+			}
+		}
+	}
+
+	if res.CreatedAt.IsZero() {
+		return record{}, fmt.Errorf("missing creation time")
+	}
+	if res.VehicleID == "" {
+		res.VehicleID = "replay" // logs written before vehicles were named
+	}
+	res.ProcessedAt = res.CreatedAt
+	return record{at: res.CreatedAt, result: &res}, nil
+}
+
+// readCSV reads a CSV of SensorData, or of ResultData when its header names any ResultData statistic.
+func readCSV(r io.Reader) ([]record, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	// Position columns belong to both, so only the statistics tell a ResultData apart
+	isResult := false
+	for _, name := range header {
+		_, result := resultFields(&model.ResultData{})[name]
+		_, sensor := sensorFields(&model.SensorData{})[name]
+		if result && !sensor {
+			isResult = true
+		}
+	}
+
+	var records []record
+	for n := 2; ; n++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var data model.SensorData
+		var res model.ResultData
+		fields, vehicleID, at := sensorFields(&data), &data.VehicleID, &data.CreatedAt
+		if isResult {
+			fields, vehicleID, at = resultFields(&res), &res.VehicleID, &res.CreatedAt
+		}
+
+		for i, value := range row {
+			if i >= len(header) {
+				break
+			}
+			switch name := header[i]; name {
+			case "vehicleid":
+				*vehicleID = value
+			case "createdat", "timestamp":
+				if *at, err = time.Parse(time.RFC3339Nano, value); err != nil {
+					return nil, fmt.Errorf("row %d: %w", n, err)
+				}
+			default:
+				if field, ok := fields[name]; ok {
+					v, err := strconv.ParseFloat(value, 32)
+					if err != nil {
+						return nil, fmt.Errorf("row %d, %s: %w", n, name, err)
+					}
+					*field = float32(v)
+				}
+			}
+		}
+		if *vehicleID == "" {
+			*vehicleID = "replay"
+		}
+
+		if isResult {
+			res.ProcessedAt = res.CreatedAt
+			records = append(records, record{at: res.CreatedAt, result: &res})
+		} else {
+			records = append(records, record{at: data.CreatedAt, sensor: &data})
+		}
+	}
+}
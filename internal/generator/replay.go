@@ -0,0 +1,307 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/clock"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
+)
+
+/*
+replayer plays a recording back at its original timing divided by speed.
+Records are due at anchorClock + (recorded time - anchorAt) / speed, so seeking or looping only moves the anchor.
+Every loop shifts the replayed timestamps by the length of the recording, keeping them increasing.
+*/
+type replayer struct {
+	source      string
+	records     []record
+	speed       float64
+	loop        bool
+	pos         int           // next record to play
+	shift       time.Duration // added to the recorded timestamps
+	anchorAt    time.Time     // recorded time played at anchorClock
+	anchorClock time.Time
+}
+
+func newReplayer(source string, records []record, speed float64, loop bool, now time.Time) *replayer {
+	if speed <= 0 {
+		speed = 1
+	}
+	return &replayer{
+		source:      source,
+		records:     records,
+		speed:       speed,
+		loop:        loop,
+		anchorAt:    records[0].at,
+		anchorClock: now,
+	}
+}
+
+// length returns the recorded time between the first and the last record.
+func (r *replayer) length() time.Duration {
+	return r.records[len(r.records)-1].at.Sub(r.records[0].at)
+}
+
+// due returns the clock time at which rec is played.
+func (r *replayer) due(rec record) time.Time {
+	return r.anchorClock.Add(time.Duration(float64(rec.at.Sub(r.anchorAt)) / r.speed))
+}
+
+// next returns the clock time at which the next record is played, and false when the recording is over.
+func (r *replayer) next() (time.Time, bool) {
+	if r.pos >= len(r.records) {
+		return time.Time{}, false
+	}
+	return r.due(r.records[r.pos]), true
+}
+
+// seek moves playback to offset from the start of the recording.
+func (r *replayer) seek(offset time.Duration, now time.Time) {
+	offset = max(0, min(offset, r.length()))
+	target := r.records[0].at.Add(offset)
+
+	r.pos = len(r.records)
+	for i, rec := range r.records {
+		if !rec.at.Before(target) {
+			r.pos = i
+			break
+		}
+	}
+	r.anchorAt, r.anchorClock = target, now
+}
+
+// rewind starts the recording over, one typical record interval after its end.
+func (r *replayer) rewind(now time.Time) {
+	gap := time.Second
+	if len(r.records) > 1 && r.records[1].at.After(r.records[0].at) {
+		gap = r.records[1].at.Sub(r.records[0].at)
+	}
+	r.shift += r.length() + gap
+	r.pos = 0
+	r.anchorAt, r.anchorClock = r.records[0].at.Add(-gap), now
+}
+
+// play returns the records due at now, with their timestamps shifted, and advances past them.
+func (r *replayer) play(now time.Time) []record {
+	var due []record
+	for r.pos < len(r.records) && !r.due(r.records[r.pos]).After(now) {
+		rec := r.records[r.pos]
+		r.pos++
+
+		if rec.sensor != nil {
+			data := *rec.sensor
+			data.CreatedAt = data.CreatedAt.Add(r.shift)
+			rec.sensor = &data
+		}
+		if rec.result != nil {
+			res := *rec.result
+			res.CreatedAt = res.CreatedAt.Add(r.shift)
+			res.ProcessedAt = res.ProcessedAt.Add(r.shift)
+			rec.result = &res
+		}
+		due = append(due, rec)
+	}
+	return due
+}
+
+// status returns the progress of playback.
+func (r *replayer) status() model.ReplayStatus {
+	position := r.length()
+	if r.pos < len(r.records) {
+		position = r.records[r.pos].at.Sub(r.records[0].at)
+	}
+	return model.ReplayStatus{
+		Source:   r.source,
+		Index:    r.pos,
+		Records:  len(r.records),
+		Position: position.Seconds(),
+		Length:   r.length().Seconds(),
+		Loop:     r.loop,
+		Finished: r.pos >= len(r.records),
+	}
+}
+
+/*
+replayVehicle returns the configuration a replayed vehicle is processed with: the configured vehicle of that ID,
+or the default sensor, with the sensor interval observed in the recording (the median spacing of its SensorData).
+*/
+func replayVehicle(vehicleID string, records []record) config.VehicleConfig {
+	vehicle := config.VehicleConfig{VehicleID: vehicleID, Sensor: config.Sensor}
+	for _, v := range config.Vehicles {
+		if v.VehicleID == vehicleID {
+			vehicle = v
+		}
+	}
+
+	var spacings []time.Duration
+	var last time.Time
+	for _, rec := range records {
+		if rec.sensor == nil || rec.sensor.VehicleID != vehicleID {
+			continue
+		}
+		if !last.IsZero() && rec.at.After(last) {
+			spacings = append(spacings, rec.at.Sub(last))
+		}
+		last = rec.at
+	}
+	if len(spacings) > 0 {
+		sort.Slice(spacings, func(i, j int) bool { return spacings[i] < spacings[j] })
+		vehicle.Sensor.Interval = spacings[len(spacings)/2]
+	}
+	return vehicle
+}
+
+/*
+Replay feeds a recording from config.Replay.Path into the pipeline instead of the configured vehicles.
+It runs every vehicle of the recording with a "replay" source of that path (see replaySource),
+handed the recording loaded once here, and processed with the configuration of replayVehicle:
+- ResultData records are pushed to outResultQueue as they were recorded, and checked against the alert rules.
+- SensorData records go through the Process of their vehicle, like simulated readings.
+- Playback follows the recorded timing divided by config.Replay.Speed, on the simulated clock,
+  so the "pause", "resume", "step" and "speed" clock Commands also drive the replay.
+
+The replay sources respond to control commands (see playback):
+- "Seek t" → jumps to t from the start of the recording (Go duration or seconds).
+- "Loop b" → starts the recording over when it ends (b: true|false, omitted toggles).
+"Raw b" switches the raw stream of the replayed SensorData (see rawStream).
+Progress is published as "replay" Events on start, seek, loop and end.
+*/
+func Replay(inCommandChan <-chan model.Command, outResultQueue *queue.Queue[model.ResultData], outEventQueue *queue.Queue[model.Event], raw *rawStream) {
+	records, err := loadRecording(config.Replay.Path)
+	if err != nil {
+		log.Printf("[ERROR][Generator][Replay] Error loading recording: %v", err)
+		for range inCommandChan {
+			// keep the command path flowing
+		}
+		return
+	}
+
+	var vehicles []config.VehicleConfig
+	seen := make(map[string]bool)
+	for _, rec := range records {
+		vehicleID := ""
+		if rec.sensor != nil {
+			vehicleID = rec.sensor.VehicleID
+		} else {
+			vehicleID = rec.result.VehicleID
+		}
+		if seen[vehicleID] {
+			continue
+		}
+		seen[vehicleID] = true
+
+		vehicle := replayVehicle(vehicleID, records)
+		vehicle.Source = config.SourceConfig{Type: "replay", Path: config.Replay.Path, Loop: config.Replay.Loop}
+		vehicles = append(vehicles, vehicle)
+	}
+
+	log.Printf("[INFO][Generator][Replay] Replaying %d records of %d vehicles from %s.", len(records), len(vehicles), config.Replay.Path)
+	runVehicles(vehicles, map[string][]record{config.Replay.Path: records}, inCommandChan, outResultQueue, outEventQueue, raw)
+}
+
+/*
+playback plays r on the simulated clock until ctx is done, handing every record to emit once it is due.
+It applies the "seek" and "loop" Commands from inCommandChan and hands any other one to other.
+Progress is reported to publish on seek, loop and end, and logged prefixed with name.
+*/
+func playback(ctx context.Context, name string, r *replayer, inCommandChan <-chan model.Command, emit func(record), other func(model.Command), publish func(string)) {
+	wake := make(chan struct{}, 1)
+	var timer *clock.Timer
+	arm := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		if at, ok := r.next(); ok {
+			timer = clock.AfterFunc(clock.Until(at), func() {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			})
+		}
+	}
+	arm()
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case cmd, ok := <-inCommandChan:
+			if !ok {
+				// No more Commands, keep playing until ctx is done
+				inCommandChan = nil
+				continue
+			}
+			now := clock.Now()
+			switch strings.ToLower(cmd.Action) {
+			case "seek":
+				offset, err := replayOffset(cmd.Params)
+				if err != nil {
+					log.Printf("[ERROR][Generator][Replay] %sInvalid seek: %v", name, err)
+					continue
+				}
+				r.seek(offset, now)
+				log.Printf("[INFO][Generator][Replay] %sSeeked to %s.", name, offset)
+				publish(fmt.Sprintf("Seeked to %s.", offset))
+			case "loop":
+				if b, ok := cmd.Params.(bool); ok {
+					r.loop = b
+				} else {
+					r.loop = !r.loop
+				}
+				if r.loop && r.pos >= len(r.records) {
+					r.rewind(now)
+				}
+				log.Printf("[INFO][Generator][Replay] %sLoop: %t.", name, r.loop)
+				publish(fmt.Sprintf("Loop: %t.", r.loop))
+			default:
+				other(cmd)
+				continue
+			}
+			arm()
+
+		case <-wake:
+			played := r.play(clock.Now())
+			for _, rec := range played {
+				emit(rec)
+			}
+
+			// The last record was just played
+			if len(played) > 0 && r.pos >= len(r.records) {
+				if r.loop {
+					r.rewind(clock.Now())
+					log.Printf("[INFO][Generator][Replay] %sLooping.", name)
+					publish("Looping.")
+				} else {
+					log.Printf("[INFO][Generator][Replay] %sFinished.", name)
+					publish("Finished.")
+				}
+			}
+			arm()
+		}
+	}
+}
+
+// replayOffset reads a seek position, a Go duration ("1m30s") or a number of seconds.
+func replayOffset(params interface{}) (time.Duration, error) {
+	switch v := params.(type) {
+	case string:
+		return time.ParseDuration(v)
+	case float64:
+		return time.Duration(v * float64(time.Second)), nil
+	}
+	return 0, fmt.Errorf("unsupported position %v", params)
+}
@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadRecordingFormats(t *testing.T) {
+	date := time.Date(2025, 10, 19, 0, 0, 0, 0, time.UTC)
+	lines := strings.Join([]string{
+		`{"Speed":12.5,"Pressure":1,"VehicleID":"123","CreatedAt":"2025-10-19T10:00:00.001Z"}`,
+		`{"action":"start"}`,
+		`{"AverageSpeed":20,"MaximumSpeed":22,"VehicleID":"123","CreatedAt":"2025-10-19T10:00:00.1Z"}`,
+		`[DATA] Vehicle: 124 | Created at 10:00:00.200000, Processed at 10:00:00.200100, Logged at 10:00:00.200200 | AvgSpeed: 59.72, MinSpeed: 59.49, MaxSpeed: 59.96`,
+	}, "\n")
+
+	records, err := readLines(strings.NewReader(lines), date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3 (the command is skipped)", len(records))
+	}
+	if records[0].sensor == nil || records[0].sensor.Speed != 12.5 {
+		t.Errorf("record 0 = %+v, want SensorData with speed 12.5", records[0])
+	}
+	if records[1].result == nil || records[1].result.MaximumSpeed != 22 {
+		t.Errorf("record 1 = %+v, want ResultData with max speed 22", records[1])
+	}
+	logged := records[2].result
+	if logged == nil || logged.VehicleID != "124" || logged.AverageSpeed != 59.72 || logged.MinimumSpeed != 59.49 {
+		t.Fatalf("record 2 = %+v, want the logged ResultData of vehicle 124", records[2].result)
+	}
+	if want := date.Add(10*time.Hour + 200*time.Millisecond); !records[2].at.Equal(want) {
+		t.Errorf("record 2 at %s, want %s", records[2].at, want)
+	}
+
+	csv := "createdAt,vehicleID,speed,batterySoC\n2025-10-19T10:00:00Z,7,3.5,80\n2025-10-19T10:00:01Z,7,4,79.9\n"
+	records, err = readCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1].sensor == nil || records[1].sensor.Speed != 4 || records[1].sensor.BatterySoC != 79.9 {
+		t.Errorf("csv records = %+v, want 2 SensorData", records)
+	}
+}
+
+func TestReplayerTimingSeekAndLoop(t *testing.T) {
+	start := time.Date(2025, 10, 19, 10, 0, 0, 0, time.UTC)
+	records, err := readCSV(strings.NewReader("createdAt,speed\n" +
+		"2025-10-19T10:00:00Z,1\n2025-10-19T10:00:01Z,2\n2025-10-19T10:00:02Z,3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// At 2x, the records are 500ms apart
+	r := newReplayer("test", records, 2, false, start)
+	if got := r.play(start); len(got) != 1 {
+		t.Fatalf("played %d records at start, want 1", len(got))
+	}
+	if got := r.play(start.Add(400 * time.Millisecond)); len(got) != 0 {
+		t.Fatalf("played %d records before they are due", len(got))
+	}
+	if got := r.play(start.Add(500 * time.Millisecond)); len(got) != 1 || got[0].sensor.Speed != 2 {
+		t.Fatalf("played %+v at 500ms, want the second record", got)
+	}
+
+	// Seeking back replays the first record at once
+	r.seek(0, start.Add(time.Second))
+	if got := r.play(start.Add(time.Second)); len(got) != 1 || got[0].sensor.Speed != 1 {
+		t.Fatalf("played %+v after seeking to 0, want the first record", got)
+	}
+
+	// Looping shifts the replayed timestamps past the end of the recording
+	r.play(start.Add(time.Hour))
+	r.rewind(start.Add(time.Hour))
+	got := r.play(start.Add(time.Hour + 500*time.Millisecond))
+	if len(got) != 1 {
+		t.Fatalf("played %d records after rewinding, want 1", len(got))
+	}
+	if want := records[2].at.Add(time.Second); !got[0].sensor.CreatedAt.Equal(want) {
+		t.Errorf("looped record created at %s, want %s", got[0].sensor.CreatedAt, want)
+	}
+}
@@ -1,146 +1,463 @@
 package generator
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"math/rand"
+	"reflect"
 	"strings"
 	"time"
 
 	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/clock"
 	"github.com/vasyl-ks/TM-software-H11/internal/model"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
 )
 
+// stateInterval defines how often the vehicle state is published besides every change, when it moved on since the last one.
+const stateInterval = time.Second
+
+// sensorChannels lists the channels a SensorData carries.
+var sensorChannels = []string{"speed", "pressure", "temperature", "batteryVoltage", "batteryCurrent", "batterySoC", "batteryTemperature"}
+
+// findMode returns the mode of modes named name, matched case-insensitively.
+func findMode(modes []config.Mode, name string) (config.Mode, bool) {
+	for _, m := range modes {
+		if strings.EqualFold(m.Name, name) {
+			return m, true
+		}
+	}
+	return config.Mode{}, false
+}
+
+// sensorChannel returns the canonical name of a channel, matched case-insensitively.
+func sensorChannel(name string) (string, bool) {
+	for _, c := range sensorChannels {
+		if strings.EqualFold(c, name) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// simulator holds the state of the simulated vehicle between sensor readings.
+type simulator struct {
+	vehicleID   string
+	cfg         config.SensorConfig
+	mode        config.Mode // current driving mode, from cfg.Modes
+	state       string      // control state, see statemachine.go
+	speed       float32     // km/h, true speed of the last step
+	targetSpeed float32     // km/h, requested through "accelerate" or "set_speed"
+	dynamics    *dynamics
+	cruise      *cruise // drives the speed toward targetSpeed
+	track       *track  // position and speed limits
+	battery     *battery
+	thermal     *thermal
+	noise       map[string]*noiseModel // by channel name
+	faults      faultInjector
+	trip        *trip              // open trip, nil outside trips
+	published   model.VehicleState // last VehicleState published
+	emit        func(model.Event)  // receives state and fault Events, may be nil
+}
+
 /*
-Sensor simulates a sensor by generating random speed, pressure and temperature
-readings every sensorInterval and sending them to the provided channel.
-
-Speed behavior responds to control commands:
-- "Start" → enables movement.
-- "Stop" → sets speed to 0.
-- "Accelerate n" → increases current speed by n.
-- "Mode" → changes driving mode (eco|normal|sport).
+newSimulator builds the simulator of vehicle. Every random draw (noise and faults) comes from rng,
+so the same rng seed and the same commands at the same times produce the same readings.
 */
-func Sensor(inCommandChan <-chan model.Command, outChan chan<- model.SensorData) {
-	sensorInterval := config.Sensor.Interval // defines how often a new sensor reading is generated.
+func newSimulator(vehicle config.VehicleConfig, rng *rand.Rand, emit func(model.Event)) *simulator {
+	cfg := vehicle.Sensor
+	noise := make(map[string]*noiseModel)
+	for channel, n := range cfg.Noise {
+		noise[channel] = newNoiseModel(n, rng)
+	}
 
-	ticker := time.NewTicker(sensorInterval)
-	defer ticker.Stop()
+	mode, _ := findMode(cfg.Modes, cfg.DefaultMode)
 
-	minS, maxS := config.Sensor.MinSpeed, config.Sensor.MaxSpeed
-	minP, maxP := config.Sensor.MinPressure, config.Sensor.MaxPressure
-	minT, maxT := config.Sensor.MinTemp, config.Sensor.MaxTemp
+	return &simulator{
+		vehicleID: vehicle.VehicleID,
+		mode:      mode,
+		state:     model.StateIdle,
+		cfg:       cfg,
+		dynamics:  newDynamics(cfg.Dynamics),
+		cruise:    newCruise(mode, cfg.Dynamics.ThrottleGain),
+		track:     newTrack(cfg.Track, cfg.Dynamics),
+		battery:   newBattery(cfg.Battery),
+		thermal:   newThermal(cfg.Thermal),
+		noise:     noise,
+		faults:    faultInjector{cfg: cfg, rng: rng},
+		emit:      emit,
+	}
+}
 
-	// vehicle state
-	currentSpeed := float32(0)
-	mode := "normal"
-	started := false
+// event hands an Event of this vehicle to emit.
+func (s *simulator) event(eventType, message string, payload interface{}, now time.Time) {
+	if s.emit == nil {
+		return
+	}
+	s.emit(model.Event{
+		Type:      eventType,
+		VehicleID: s.vehicleID,
+		Message:   message,
+		Payload:   payload,
+		CreatedAt: now,
+	})
+}
 
-	log.Println("[INFO][Generator][Sensor] Running.")
+// snapshot returns the VehicleState of the vehicle.
+func (s *simulator) snapshot(now time.Time) model.VehicleState {
+	limit, _ := s.track.limit(s.dynamics.speedKmh())
+	return model.VehicleState{
+		VehicleID:     s.vehicleID,
+		State:         s.state,
+		Started:       s.canDrive(),
+		Mode:          s.mode.Name,
+		Speed:         s.speed,
+		TargetSpeed:   s.targetSpeed,
+		SpeedError:    float32(s.cruise.err),
+		Throttle:      float32(s.cruise.throttle),
+		Position:      s.track.position,
+		DistanceToEnd: s.track.distanceToEnd(),
+		SpeedLimit:    limit,
+		Segment:       s.track.segment(),
+		Station:       s.track.station(),
+		Faults:        s.faults.list(),
+		Trip:          s.trip.id(),
+		UpdatedAt:     now,
+	}
+}
 
-	for {
-		select {
-		case cmd := <-inCommandChan:
-			switch strings.ToLower(cmd.Action) {
-			case "start":
-				started = true
-				log.Println("[INFO][Generator][Sensor] Started.")
-			case "stop":
-				started = false
-				currentSpeed = 0
-				log.Println("[INFO][Generator][Sensor] Stopped.")
-			case "accelerate":
-				// Try to read numeric parameter
-				if val, ok := cmd.Params.(float64); ok {
-					currentSpeed += float32(val)
-					log.Printf("[INFO][Generator][Sensor] Accelerated by %f.", val)
-				}
-			case "mode":
-				if val, ok := cmd.Params.(string); ok {
-					mode = strings.ToLower(val)
-					log.Printf("[INFO][Generator][Sensor] Mode changed to %s.", val)
-				}
-			}
+// publishState emits the current VehicleState.
+func (s *simulator) publishState(now time.Time) {
+	s.published = s.snapshot(now)
+	s.event(model.EventState, "", s.published, now)
+}
 
-		case <-ticker.C:
-			// adjust max speed depending on mode
-			var maxAllowed float32
-			switch mode {
-			case "eco":
-				maxAllowed = maxS * 0.5
-			case "normal":
-				maxAllowed = maxS * 0.8
-			case "sport":
-				maxAllowed = maxS
-			default:
-				maxAllowed = maxS * 0.8
-			}
+// refreshState emits the current VehicleState unless it is the one last published, e.g. at rest.
+func (s *simulator) refreshState(now time.Time) {
+	state := s.snapshot(now)
+	state.UpdatedAt = s.published.UpdatedAt
+	if reflect.DeepEqual(state, s.published) {
+		return
+	}
+	s.publishState(now)
+}
 
-			// adjust growth factors based on mode
-			var growthFactor float32
-			switch mode {
-			case "eco":
-				growthFactor = 0.7 // slowest growth
-			case "normal":
-				growthFactor = 1.0 // standard growth
-			case "sport":
-				growthFactor = 1.3 // fastest growth
-			default:
-				growthFactor = 1.0
-			}
+// injectFault activates the fault described by params, or clears faults when its type is "clear".
+func (s *simulator) injectFault(params interface{}, now time.Time) {
+	fault, err := parseFault(params, now)
+	if err != nil {
+		log.Printf("[ERROR][Generator][Sensor] %s invalid fault: %v", s.vehicleID, err)
+		return
+	}
 
-			// simulate speed
-			if started {
-				if currentSpeed < minS {
-					currentSpeed = minS
-				}
-				if currentSpeed > maxAllowed {
-					currentSpeed = maxAllowed
-				}
-			} else {
-				currentSpeed = 0
-			}
+	if fault.Type == "clear" {
+		for _, f := range s.faults.clear(fault.Channel) {
+			s.event(model.EventFault, fmt.Sprintf("Cleared %s fault on %s.", f.Type, f.Channel), f, now)
+		}
+		log.Printf("[INFO][Generator][Sensor] %s faults cleared on %s.", s.vehicleID, fault.Channel)
+		return
+	}
 
-			// Normalize the current speed into a [0,1] range
-			// 0 means minimum speed, 1 means maximum speed
-			speedRatio := (currentSpeed - minS) / (maxS - minS)
-			if speedRatio < 0 {
-				speedRatio = 0
-			} else if speedRatio > 1 {
-				speedRatio = 1
-			}
+	s.faults.add(fault)
+	s.event(model.EventFault, fmt.Sprintf("Injected %s fault on %s.", fault.Type, fault.Channel), fault, now)
+	log.Printf("[INFO][Generator][Sensor] %s injected %s fault on %s.", s.vehicleID, fault.Type, fault.Channel)
+}
 
-			// Make pressure and temperature increase with speed
-			// Both grow linearly from their minimum to maximum values
-			// They grow faster on the sport mode and slower on eco.
-			pressure := minP + (speedRatio*growthFactor)*(maxP-minP)
-			temperature := minT + (speedRatio*growthFactor)*(maxT-minT)
+// read returns what the sensor of channel reads when the true value is value.
+func (s *simulator) read(channel string, value float32, dt time.Duration) float32 {
+	n, ok := s.noise[channel]
+	if !ok {
+		return value
+	}
+	return float32(n.apply(float64(value), dt.Seconds()))
+}
 
-			// Add a small random noise to simulate sensor variability
-			pressure += rand.Float32()*0.1 - 0.05
-			temperature += rand.Float32()*0.5 - 0.25
+// sense returns the reading of channel: its noise model first, then the injected faults.
+func (s *simulator) sense(channel string, value float32, now time.Time, dt time.Duration) float32 {
+	return s.faults.apply(channel, s.read(channel, value, dt), now)
+}
 
-			// Adjust pressure to avoid negative values
-			if pressure < 0 {
-				pressure = 0
-			}
+/*
+handle applies a control Command to the vehicle and publishes the new state.
+A Command the current control state does not allow is rejected with a "rejected" Event carrying the reason.
+Params are checked again here, so a Command reject lets through by mistake is logged and ignored instead of panicking.
+*/
+func (s *simulator) handle(cmd model.Command, now time.Time) {
+	if reason := s.reject(cmd); reason != "" {
+		rejection := model.CommandRejection{Command: cmd, State: s.state, Reason: reason}
+		s.event(model.EventRejected, fmt.Sprintf("Rejected %s: %s.", cmd.Action, reason), rejection, now)
+		log.Printf("[WARN][Generator][Sensor] %s rejected %s: %s.", s.vehicleID, cmd.Action, reason)
+		s.trip.command(true)
+		return
+	}
+	defer s.publishState(now)
+	s.trip.command(false)
 
-			// Adjust pressure to avoid values above maximum.
-			if pressure > maxP {
-				pressure = maxP
-			}
-			if temperature > maxT {
-				temperature = maxT
+	switch strings.ToLower(cmd.Action) {
+	case "start":
+		s.transition(model.StateReady, cmd.Action, now)
+		s.openTrip(now)
+	case "stop":
+		s.targetSpeed = 0
+		if s.state == model.StateRunning {
+			s.transition(model.StateBraking, cmd.Action, now)
+		} else {
+			s.transition(model.StateIdle, cmd.Action, now)
+		}
+		s.closeTrip(cmd.Action, now)
+	case "accelerate":
+		val, ok := cmd.Params.(float64)
+		if !ok {
+			log.Printf("[ERROR][Generator][Sensor] %s invalid accelerate amount %v.", s.vehicleID, cmd.Params)
+			return
+		}
+		s.targetSpeed += float32(val)
+		s.transition(model.StateRunning, cmd.Action, now)
+		log.Printf("[INFO][Generator][Sensor] %s accelerated by %f.", s.vehicleID, val)
+	case "set_speed":
+		val, ok := cmd.Params.(float64)
+		if !ok {
+			log.Printf("[ERROR][Generator][Sensor] %s invalid target speed %v.", s.vehicleID, cmd.Params)
+			return
+		}
+		s.targetSpeed = float32(val)
+		s.transition(model.StateRunning, cmd.Action, now)
+		log.Printf("[INFO][Generator][Sensor] %s holding %f km/h.", s.vehicleID, val)
+	case "emergencystop":
+		s.targetSpeed = 0
+		s.transition(model.StateEmergencyStopped, cmd.Action, now)
+	case "reset":
+		if s.state == model.StateIdle {
+			s.track.position = 0
+			log.Printf("[INFO][Generator][Sensor] %s returned to the start of the track.", s.vehicleID)
+		}
+		s.transition(model.StateIdle, cmd.Action, now)
+	case "mode":
+		name, _ := cmd.Params.(string)
+		mode, ok := findMode(s.cfg.Modes, name)
+		if !ok {
+			log.Printf("[ERROR][Generator][Sensor] %s invalid mode %v.", s.vehicleID, cmd.Params)
+			return
+		}
+		previous := s.mode.Name
+		s.mode = mode
+		if s.mode.Name != previous {
+			s.trip.modeChange()
+		}
+		s.cruise.setGains(s.mode.PID, s.cfg.Dynamics.ThrottleGain)
+		log.Printf("[INFO][Generator][Sensor] %s mode changed to %s.", s.vehicleID, s.mode.Name)
+	case "fault":
+		s.injectFault(cmd.Params, now)
+	}
+}
+
+/*
+step advances the vehicle by dt and returns the SensorData read at now.
+It reports false when a dropout fault silences the sensor and no SensorData is emitted.
+*/
+func (s *simulator) step(now time.Time, dt time.Duration) (model.SensorData, bool) {
+	// Expire faults whose duration elapsed
+	if expired := s.faults.expire(now); len(expired) > 0 {
+		for _, f := range expired {
+			s.event(model.EventFault, fmt.Sprintf("Expired %s fault on %s.", f.Type, f.Channel), f, now)
+		}
+		s.publishState(now)
+	}
+
+	minS, maxS := s.cfg.MinSpeed, s.cfg.MaxSpeed
+	minP, maxP := s.cfg.MinPressure, s.cfg.MaxPressure
+	maxT := s.cfg.MaxTemp
+
+	// adjust max speed and pressure growth depending on mode
+	maxAllowed := maxS * s.mode.SpeedCap
+	growthFactor := s.mode.GrowthFactor
+
+	// The target stays within the mode limits; in any other state than ready or running the vehicle brakes to rest
+	throttle := -1.0
+	if s.canDrive() {
+		if s.targetSpeed < minS {
+			s.targetSpeed = minS
+		}
+		if s.targetSpeed > maxAllowed {
+			s.targetSpeed = maxAllowed
+		}
+		// Segment limits and the end of the track lower the target the controller drives toward, not the requested one
+		target := s.targetSpeed
+		if limit, ok := s.track.limit(s.dynamics.speedKmh()); ok && limit < target {
+			target = limit
+		}
+		throttle = s.cruise.update(float64(target), s.dynamics.speedKmh(), dt.Seconds())
+	} else {
+		s.targetSpeed = 0
+		s.cruise.reset()
+	}
+
+	// An empty battery can no longer drive the motors
+	if s.battery.empty() && throttle > 0 {
+		throttle = 0
+	}
+
+	// simulate speed, the battery supplying it and the heat it produces
+	currentSpeed := float32(s.dynamics.step(throttle, s.mode.AccelerationLimit, dt.Seconds()))
+	if s.track.advance(float64(currentSpeed), dt.Seconds()) && currentSpeed > 0 {
+		// The buffer at the end of the track stops the vehicle, whatever its speed
+		log.Printf("[WARN][Generator][Sensor] %s hit the end of the track at %.1f km/h.", s.vehicleID, currentSpeed)
+		s.dynamics.stop()
+		currentSpeed = 0
+	}
+	s.speed = currentSpeed
+	s.battery.step(s.dynamics.power(), dt.Seconds())
+	temperature := float32(s.thermal.step(s.dynamics.power(), s.mode, dt.Seconds()))
+
+	// Stopping, coming to rest and faults move the control state on their own
+	if s.settle(now) {
+		s.publishState(now)
+	}
+
+	// Normalize the current speed into a [0,1] range
+	// 0 means minimum speed, 1 means maximum speed
+	speedRatio := (currentSpeed - minS) / (maxS - minS)
+	if speedRatio < 0 {
+		speedRatio = 0
+	} else if speedRatio > 1 {
+		speedRatio = 1
+	}
+
+	// Make pressure increase with speed
+	// It grows linearly from its minimum to maximum value
+	// It grows faster on modes with a higher growth factor.
+	pressure := minP + (speedRatio*growthFactor)*(maxP-minP)
+
+	// A dropout on every channel silences the sensor
+	if s.faults.dropsFrame() {
+		return model.SensorData{}, false
+	}
+
+	// Read every channel through its noise model and injected faults to simulate sensor variability
+	speed := s.sense("speed", currentSpeed, now, dt)
+	pressure = s.sense("pressure", pressure, now, dt)
+	temperature = s.sense("temperature", temperature, now, dt)
+	batteryVoltage := s.sense("batteryVoltage", float32(s.battery.voltage), now, dt)
+	batteryCurrent := s.sense("batteryCurrent", float32(s.battery.current), now, dt)
+	batterySoC := s.sense("batterySoC", float32(s.battery.soc*100), now, dt)
+	batteryTemperature := s.sense("batteryTemperature", float32(s.battery.temperature), now, dt)
+
+	// Adjust speed and pressure to avoid negative values
+	if speed < 0 {
+		speed = 0
+	}
+	if pressure < 0 {
+		pressure = 0
+	}
+
+	// Adjust pressure to avoid values above maximum.
+	if pressure > maxP {
+		pressure = maxP
+	}
+	if temperature > maxT {
+		temperature = maxT
+	}
+
+	// Create SensorData, stamped with the open trip
+	data := model.SensorData{
+		VehicleID:          s.vehicleID,
+		Speed:              speed,
+		Pressure:           pressure,
+		Temperature:        temperature,
+		BatteryVoltage:     batteryVoltage,
+		BatteryCurrent:     batteryCurrent,
+		BatterySoC:         batterySoC,
+		BatteryTemperature: batteryTemperature,
+		Position:           float32(s.track.position),
+		DistanceToEnd:      float32(s.track.distanceToEnd()),
+		CreatedAt:          now,
+	}
+	s.trip.add(&data, s.track.position)
+	return data, true
+}
+
+/*
+Sensor simulates the sensors of one vehicle by generating speed, pressure and temperature
+readings every sensorInterval of its config and pushing them to the provided queue.
+
+Speed is integrated each tick by a vehicle dynamics model (traction, brakes,
+aerodynamic drag and rolling resistance) whose throttle and brake are driven toward
+the target speed by the PID controller of the mode, and responds to control commands,
+each validated against the control state machine (see statemachine.go):
+- "Start" → enables traction.
+- "Stop" → brakes the vehicle to rest.
+- "Accelerate n" → raises the target speed by n.
+- "Set_speed n" → sets the target speed to n km/h, which the vehicle then holds.
+- "EmergencyStop" → brakes at full force until "Reset".
+- "Reset" → while idle, returns the vehicle to the start of the track.
+- "Mode" → changes driving mode to one of the configured modes, which caps the target speed,
+  limits the acceleration and sets how fast pressure and temperature respond.
+
+A battery pack supplies the traction power: its voltage sags under load, its state of charge
+drains with the current drawn and its cells heat up with the resistive losses.
+Temperature follows the traction power with the heating and cooling time constants of the mode,
+so it rises gradually under load and cools toward ambient once the vehicle stops.
+
+Position is integrated from the speed along the configured track. Segment speed limits are
+enforced automatically, braking ahead of a slower segment, and the vehicle brakes to a stop
+in the braking zone before the end of the track.
+
+Every channel is read through its configured noise model (bias, drift, white noise,
+quantization and sample-and-hold), so readings look like a real acquisition chain.
+
+Readings are stamped with simulated time, start plus one sensorInterval per tick, and commands
+take effect at the current simulated time. Together with the per-vehicle rng, a seeded run replays
+the same SensorData for the same command timeline.
+
+Every accepted "Start" opens a trip, stamped on the readings, and "Stop" closes it (see trip.go):
+its TripSummary is pushed as a "trip" Event to outEventQueue.
+
+"Fault" commands inject sensor faults (dropout, stuck, spike, nan, drift, delay) on a channel
+for a duration. Fault changes and the VehicleState (on every change, and every stateInterval
+while it keeps changing, e.g. while driving) are pushed as Events to outEventQueue. Sensor runs until ctx is done.
+*/
+func Sensor(ctx context.Context, vehicle config.VehicleConfig, rng *rand.Rand, start time.Time, inCommandChan <-chan model.Command, outQueue *queue.Queue[model.SensorData], outEventQueue *queue.Queue[model.Event]) {
+	sensorInterval := vehicle.Sensor.Interval // defines how often a new sensor reading is generated.
+
+	ticker := clock.NewTicker(sensorInterval)
+	defer ticker.Stop()
+	stateTicker := clock.NewTicker(stateInterval)
+	defer stateTicker.Stop()
+
+	sim := newSimulator(vehicle, rng, func(e model.Event) { outEventQueue.Push(e) })
+
+	log.Printf("[INFO][Generator][Sensor] %s running.", vehicle.VehicleID)
+
+	// Simulated time advances by exactly sensorInterval per step, whatever the ticker jitter.
+	// Ticks the ticker dropped are caught up, so simulated time keeps pace with the clock, even when stepped or sped up.
+	var ticks int64
+	now := start
+	running := clock.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case cmd, ok := <-inCommandChan:
+			if !ok {
+				// No more Commands, keep sensing until ctx is done
+				inCommandChan = nil
+				continue
 			}
+			sim.handle(cmd, now)
 
-			// Create SensorData
-			data := model.SensorData{
-				VehicleID:   config.Vehicle.VehicleID,
-				Speed:       currentSpeed,
-				Pressure:    pressure,
-				Temperature: temperature,
-				CreatedAt:   time.Now().Local(),
+		case <-ticker.C:
+			for due := int64(clock.Since(running) / sensorInterval); ticks < due; {
+				ticks++
+				now = start.Add(time.Duration(ticks) * sensorInterval)
+				if data, ok := sim.step(now, sensorInterval); ok {
+					outQueue.Push(data)
+				}
 			}
-			outChan <- data
+
+		case <-stateTicker.C:
+			sim.refreshState(now)
 		}
 	}
 }
@@ -0,0 +1,236 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/clock"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
+)
+
+/*
+SensorSource produces the SensorData of one vehicle.
+Run pushes its readings to out and receives the Commands routed to the vehicle, until ctx is done.
+Whatever the source, the readings go through the same Process and reach the Hub the same way.
+*/
+type SensorSource interface {
+	Run(ctx context.Context, commands <-chan model.Command, out *queue.Queue[model.SensorData])
+}
+
+// SourceEnv is what the Generator hands every SourceFactory.
+type SourceEnv struct {
+	Start   time.Time                      // simulated start time, shared by every vehicle
+	Events  *queue.Queue[model.Event]      // for the Events the source publishes, such as states and faults
+	Results *queue.Queue[model.ResultData] // for ResultData the source has already, such as recorded ones, bypassing Process
+
+	recordings map[string][]record // recordings already loaded, by path, so replay sources do not parse them again
+}
+
+// SourceFactory builds the SensorSource of a vehicle from its configuration (vehicle.Source).
+type SourceFactory func(vehicle config.VehicleConfig, env SourceEnv) (SensorSource, error)
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = make(map[string]SourceFactory)
+)
+
+// RegisterSource makes a SensorSource type available to the "source" sections of the config.
+func RegisterSource(kind string, factory SourceFactory) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	sources[kind] = factory
+}
+
+// NewSource builds the SensorSource of vehicle using the registered factory of its type; no type is the simulator.
+func NewSource(vehicle config.VehicleConfig, env SourceEnv) (SensorSource, error) {
+	kind := vehicle.Source.Type
+	if kind == "" {
+		kind = "simulator"
+	}
+	sourcesMu.RLock()
+	factory, ok := sources[kind]
+	sourcesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown source type %q", kind)
+	}
+	return factory(vehicle, env)
+}
+
+func init() {
+	RegisterSource("simulator", newSimulatorSource)
+	RegisterSource("replay", newReplaySource)
+	RegisterSource("stub", newStubSource)
+}
+
+// simulatorSource runs the synthetic Sensor of a vehicle, drawing from its own random generator.
+type simulatorSource struct {
+	vehicle config.VehicleConfig
+	rng     *rand.Rand
+	start   time.Time
+	events  *queue.Queue[model.Event]
+}
+
+func newSimulatorSource(vehicle config.VehicleConfig, env SourceEnv) (SensorSource, error) {
+	return &simulatorSource{
+		vehicle: vehicle,
+		rng:     rand.New(rand.NewSource(vehicleSeed(config.Simulation.Seed, vehicle.VehicleID))),
+		start:   env.Start,
+		events:  env.Events,
+	}, nil
+}
+
+func (s *simulatorSource) Run(ctx context.Context, commands <-chan model.Command, out *queue.Queue[model.SensorData]) {
+	Sensor(ctx, s.vehicle, s.rng, s.start, commands, out, s.events)
+}
+
+/*
+replaySource plays the records of its vehicle from a recording, at the recorded timing divided by
+config.Replay.Speed, responding to the "seek" and "loop" Commands (see playback).
+- SensorData records go to its Process; recorded ResultData go to env.Results and are checked against the alert rules.
+- It plays the whole recording and skips the records of other vehicles, so the vehicles of one recording stay in step.
+- A recording without records of the vehicle plays all of its SensorData as the vehicle's, e.g. one vehicle's recording.
+*/
+type replaySource struct {
+	vehicleID string
+	path      string
+	loop      bool
+	records   []record
+	count     int  // records played as the vehicle's
+	restamp   bool // every SensorData record is played as the vehicle's
+	results   *queue.Queue[model.ResultData]
+	events    *queue.Queue[model.Event]
+}
+
+func newReplaySource(vehicle config.VehicleConfig, env SourceEnv) (SensorSource, error) {
+	if vehicle.Source.Path == "" {
+		return nil, errors.New("replay source requires a path")
+	}
+	// Replay hands over the recording it loaded; a source configured on its own loads it
+	records, ok := env.recordings[vehicle.Source.Path]
+	if !ok {
+		var err error
+		if records, err = loadRecording(vehicle.Source.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &replaySource{vehicleID: vehicle.VehicleID, path: vehicle.Source.Path, loop: vehicle.Source.Loop, records: records, results: env.Results, events: env.Events}
+	var sensors int
+	for _, rec := range records {
+		if rec.sensor != nil {
+			sensors++
+		}
+		if s.owns(rec) {
+			s.count++
+		}
+	}
+	if s.count == 0 {
+		s.restamp, s.count = true, sensors
+	}
+	if s.count == 0 {
+		return nil, fmt.Errorf("no SensorData in %s", s.path)
+	}
+	return s, nil
+}
+
+// owns reports whether rec is played as a record of the vehicle.
+func (s *replaySource) owns(rec record) bool {
+	if rec.sensor != nil {
+		return s.restamp || rec.sensor.VehicleID == s.vehicleID
+	}
+	return !s.restamp && rec.result != nil && rec.result.VehicleID == s.vehicleID
+}
+
+func (s *replaySource) Run(ctx context.Context, commands <-chan model.Command, out *queue.Queue[model.SensorData]) {
+	r := newReplayer(s.path, s.records, config.Replay.Speed, s.loop, clock.Now())
+	publish := func(message string) {
+		s.events.Push(model.Event{Type: model.EventReplay, VehicleID: s.vehicleID, Message: message, Payload: r.status(), CreatedAt: clock.Now()})
+	}
+
+	// Recorded ResultData are checked against the alert rules of the vehicle, like processed ones
+	alarms := newAlerting(s.vehicleID, config.Alerts.Rules, func(e model.Event) { s.events.Push(e) })
+	emit := func(rec record) {
+		switch {
+		case !s.owns(rec):
+		case rec.sensor != nil:
+			data := *rec.sensor
+			data.VehicleID = s.vehicleID
+			out.Push(data)
+		case s.results != nil:
+			s.results.Push(*rec.result)
+			alarms.result(*rec.result)
+		}
+	}
+	other := func(cmd model.Command) {
+		log.Printf("[INFO][Generator][Replay] %s ignoring %s while replaying.", s.vehicleID, cmd.Action)
+	}
+
+	log.Printf("[INFO][Generator][Replay] %s replaying %d records (%s) from %s at %gx.", s.vehicleID, s.count, r.length(), s.path, r.speed)
+	publish(fmt.Sprintf("Replaying %s.", s.path))
+	playback(ctx, s.vehicleID+" ", r, commands, emit, other, publish)
+}
+
+/*
+stubSource emits the same reading every sensor interval of its vehicle, on the simulated clock,
+and ignores Commands: a fixed input for testing what follows the sensor.
+*/
+type stubSource struct {
+	reading  model.SensorData
+	interval time.Duration
+	start    time.Time
+}
+
+func newStubSource(vehicle config.VehicleConfig, env SourceEnv) (SensorSource, error) {
+	if vehicle.Sensor.Interval <= 0 {
+		return nil, errors.New("stub source requires a sensor interval")
+	}
+	reading := model.SensorData{VehicleID: vehicle.VehicleID}
+	fields := sensorFields(&reading)
+	for name, value := range vehicle.Source.Values {
+		field, ok := fields[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown channel %q", name)
+		}
+		*field = float32(value)
+	}
+	return &stubSource{reading: reading, interval: vehicle.Sensor.Interval, start: env.Start}, nil
+}
+
+func (s *stubSource) Run(ctx context.Context, commands <-chan model.Command, out *queue.Queue[model.SensorData]) {
+	ticker := clock.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	log.Printf("[INFO][Generator][Stub] %s running.", s.reading.VehicleID)
+
+	// Like Sensor, one reading per interval of simulated time, catching up dropped ticks
+	var ticks int64
+	running := clock.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case _, ok := <-commands:
+			// a stub does not react to Commands
+			if !ok {
+				commands = nil
+			}
+
+		case <-ticker.C:
+			for due := int64(clock.Since(running) / s.interval); ticks < due; {
+				ticks++
+				data := s.reading
+				data.CreatedAt = s.start.Add(time.Duration(ticks) * s.interval)
+				out.Push(data)
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
+)
+
+// fixedSource pushes its readings once, for any vehicle.
+type fixedSource []model.SensorData
+
+func (s fixedSource) Run(ctx context.Context, commands <-chan model.Command, out *queue.Queue[model.SensorData]) {
+	for _, data := range s {
+		out.Push(data)
+	}
+}
+
+func TestSensorSources(t *testing.T) {
+	defer func(channels map[string]config.ChannelConfig) { config.Pipeline.Channels = channels }(config.Pipeline.Channels)
+	config.Pipeline.Channels = map[string]config.ChannelConfig{"test.source": {Capacity: 1000, Overflow: config.OverflowDropOldest}}
+	env := SourceEnv{Start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	vehicle := goldenVehicle
+
+	// Registered types are selected by the source of the vehicle, unknown ones are refused
+	RegisterSource("fixed", func(v config.VehicleConfig, env SourceEnv) (SensorSource, error) {
+		return fixedSource{{VehicleID: v.VehicleID, Speed: 42}}, nil
+	})
+	defer func() {
+		sourcesMu.Lock()
+		delete(sources, "fixed")
+		sourcesMu.Unlock()
+	}()
+	vehicle.Source = config.SourceConfig{Type: "fixed"}
+	source, err := NewSource(vehicle, env)
+	if err != nil {
+		t.Fatalf("NewSource(fixed): %v", err)
+	}
+	out := queue.New[model.SensorData]("test.source")
+	source.Run(context.Background(), nil, out)
+	if data := <-out.Out(); data.VehicleID != "golden" || data.Speed != 42 {
+		t.Errorf("fixed source read %+v, want 42 km/h of golden", data)
+	}
+
+	vehicle.Source = config.SourceConfig{Type: "hardware"}
+	if _, err := NewSource(vehicle, env); err == nil {
+		t.Error("NewSource(hardware) succeeded, want an unknown source type")
+	}
+	vehicle.Source = config.SourceConfig{Type: "stub", Values: map[string]float64{"sPeed": 30, "unknown": 1}}
+	if _, err := NewSource(vehicle, env); err == nil {
+		t.Error("NewSource(stub) with an unknown channel succeeded")
+	}
+
+	// The stub reads its values every sensor interval of simulated time, until cancelled
+	vehicle.Source = config.SourceConfig{Type: "stub", Values: map[string]float64{"sPeed": 30, "temperature": 21.5}}
+	source, err = NewSource(vehicle, env)
+	if err != nil {
+		t.Fatalf("NewSource(stub): %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		source.Run(ctx, make(chan model.Command), out)
+		close(done)
+	}()
+
+	for i := 1; i <= 3; i++ {
+		data := <-out.Out()
+		want := model.SensorData{VehicleID: "golden", Speed: 30, Temperature: 21.5, CreatedAt: env.Start.Add(time.Duration(i) * vehicle.Sensor.Interval)}
+		if data != want {
+			t.Fatalf("stub reading %d = %+v, want %+v", i, data, want)
+		}
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stub source kept running after its context was cancelled")
+	}
+}
+
+func TestReplaySource(t *testing.T) {
+	defer func(channels map[string]config.ChannelConfig, speed float64) {
+		config.Pipeline.Channels, config.Replay.Speed = channels, speed
+	}(config.Pipeline.Channels, config.Replay.Speed)
+	config.Pipeline.Channels = map[string]config.ChannelConfig{"test.replay": {Capacity: 1000, Overflow: config.OverflowDropOldest}}
+	config.Replay.Speed = 100
+
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	lines := `{"Speed":1,"VehicleID":"a","CreatedAt":"2025-10-19T10:00:00Z"}
+{"Speed":2,"VehicleID":"b","CreatedAt":"2025-10-19T10:00:00.5Z"}
+{"AverageSpeed":1,"VehicleID":"a","CreatedAt":"2025-10-19T10:00:01Z"}
+{"Speed":3,"VehicleID":"a","CreatedAt":"2025-10-19T10:00:01Z"}
+`
+	if err := os.WriteFile(path, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := SourceEnv{Events: queue.New[model.Event]("test.replay"), Results: queue.New[model.ResultData]("test.replay")}
+	if _, err := NewSource(config.VehicleConfig{VehicleID: "a", Source: config.SourceConfig{Type: "replay"}}, env); err == nil {
+		t.Error("replay source without a path succeeded")
+	}
+
+	// Each vehicle plays its own records; one absent from the recording plays all of its readings
+	play := func(vehicleID string) (speeds []float32, results int) {
+		source, err := NewSource(config.VehicleConfig{VehicleID: vehicleID, Source: config.SourceConfig{Type: "replay", Path: path}}, env)
+		if err != nil {
+			t.Fatalf("NewSource(replay) for %s: %v", vehicleID, err)
+		}
+		out := queue.New[model.SensorData]("test.replay")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go source.Run(ctx, make(chan model.Command), out)
+
+		for {
+			select {
+			case data := <-out.Out():
+				if data.VehicleID != vehicleID {
+					t.Errorf("%s replayed a reading of %s", vehicleID, data.VehicleID)
+				}
+				speeds = append(speeds, data.Speed)
+			case res := <-env.Results.Out():
+				if res.VehicleID != vehicleID {
+					t.Errorf("%s replayed a result of %s", vehicleID, res.VehicleID)
+				}
+				results++
+			case <-time.After(200 * time.Millisecond):
+				return speeds, results
+			}
+		}
+	}
+
+	if speeds, results := play("a"); len(speeds) != 2 || speeds[1] != 3 || results != 1 {
+		t.Errorf("a replayed speeds %v and %d results, want [1 3] and 1", speeds, results)
+	}
+	if speeds, results := play("b"); len(speeds) != 1 || speeds[0] != 2 || results != 0 {
+		t.Errorf("b replayed speeds %v and %d results, want [2] and 0", speeds, results)
+	}
+	if speeds, results := play("c"); len(speeds) != 3 || results != 0 {
+		t.Errorf("c replayed speeds %v and %d results, want all 3 readings only", speeds, results)
+	}
+
+	// A recording handed over by Replay is not loaded again
+	records, err := loadRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := env
+	loaded.recordings = map[string][]record{"loaded.jsonl": records}
+	if _, err := NewSource(config.VehicleConfig{VehicleID: "a", Source: config.SourceConfig{Type: "replay", Path: "loaded.jsonl"}}, loaded); err != nil {
+		t.Errorf("replay source of a loaded recording: %v", err)
+	}
+	if _, err := NewSource(config.VehicleConfig{VehicleID: "a", Source: config.SourceConfig{Type: "replay", Path: "loaded.jsonl"}}, env); err == nil {
+		t.Error("replay source of a missing file succeeded")
+	}
+}
@@ -0,0 +1,158 @@
+package generator
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+/*
+The control state of a simulated vehicle follows this state machine:
+- Idle → Ready: "start".
+- Ready → Running: "accelerate" with a positive amount, or "set_speed" with a positive target. Ready → Idle: "stop".
+- Running → Braking: "stop", or automatically on entering the braking zone at the end of the track.
+  Running → Ready: automatically, once at rest with a zero target.
+- Braking → Idle: automatically, once at rest.
+- any → EmergencyStopped: "emergencyStop". The vehicle brakes at full force.
+- Ready, Running, Braking → Fault: automatically, on a fault of the speed sensor or an empty battery.
+- EmergencyStopped, Fault → Idle: "reset", once at rest (and, for Fault, once its cause cleared).
+  In Idle, "reset" returns the vehicle to the start of the track.
+"accelerate" and "set_speed" are rejected in the braking zone.
+"mode" is accepted in every state, but not while running faster than the cap of the new mode.
+"fault" commands inject sensor faults and are accepted in every state.
+*/
+
+// canDrive reports whether traction follows the target speed in the current state.
+func (s *simulator) canDrive() bool {
+	return s.state == model.StateReady || s.state == model.StateRunning
+}
+
+// faultCause returns why the vehicle cannot be driven safely, or "" when it can.
+func (s *simulator) faultCause() string {
+	if s.battery.empty() {
+		return "battery empty"
+	}
+	for _, f := range s.faults.list() {
+		if f.Channel == "speed" || f.Channel == faultAllChannels {
+			return fmt.Sprintf("%s fault on %s", f.Type, f.Channel)
+		}
+	}
+	return ""
+}
+
+// reject returns why cmd is not allowed in the current state, or "" when it is.
+func (s *simulator) reject(cmd model.Command) string {
+	action := strings.ToLower(cmd.Action)
+	switch action {
+	case "fault", "mode":
+	case "start", "stop", "accelerate", "set_speed", "reset":
+		switch s.state {
+		case model.StateEmergencyStopped, model.StateFault:
+			if action != "reset" {
+				return fmt.Sprintf("vehicle is in %s, reset it first", s.state)
+			}
+		}
+	case "emergencystop":
+		return ""
+	default:
+		return "unknown command"
+	}
+
+	switch action {
+	case "start":
+		if s.state != model.StateIdle {
+			return fmt.Sprintf("vehicle is already %s", s.state)
+		}
+	case "stop":
+		if !s.canDrive() {
+			return fmt.Sprintf("vehicle is already %s", s.state)
+		}
+	case "accelerate":
+		val, ok := cmd.Params.(float64)
+		switch {
+		case !ok:
+			return "accelerate needs a numeric amount"
+		case !s.canDrive():
+			return fmt.Sprintf("vehicle is %s, start it first", s.state)
+		case s.state == model.StateReady && val <= 0:
+			return "vehicle is at rest, accelerate by a positive amount"
+		case s.track.inBrakingZone():
+			return "vehicle is in the braking zone, reset it to return to the start of the track"
+		}
+	case "set_speed":
+		val, ok := cmd.Params.(float64)
+		switch {
+		case !ok || val < 0:
+			return "set_speed needs a target speed of 0 km/h or more"
+		case !s.canDrive():
+			return fmt.Sprintf("vehicle is %s, start it first", s.state)
+		case s.state == model.StateReady && val == 0:
+			return "vehicle is already at rest"
+		case s.track.inBrakingZone():
+			return "vehicle is in the braking zone, reset it to return to the start of the track"
+		}
+	case "reset":
+		switch {
+		case s.state == model.StateIdle && s.track.position > 0:
+		case s.state != model.StateEmergencyStopped && s.state != model.StateFault:
+			return fmt.Sprintf("vehicle is %s, nothing to reset", s.state)
+		case s.speed > 0:
+			return "vehicle is still moving"
+		case s.state == model.StateFault && s.faultCause() != "":
+			return fmt.Sprintf("%s persists", s.faultCause())
+		}
+	case "mode":
+		val, _ := cmd.Params.(string)
+		mode, ok := findMode(s.cfg.Modes, val)
+		if !ok {
+			return fmt.Sprintf("unknown mode %q", val)
+		}
+		if limit := s.cfg.MaxSpeed * mode.SpeedCap; s.state == model.StateRunning && s.speed > limit {
+			return fmt.Sprintf("speed %.1f km/h exceeds the %s cap of %.1f km/h", s.speed, mode.Name, limit)
+		}
+	}
+	return ""
+}
+
+// transition moves the vehicle to state and publishes the change.
+func (s *simulator) transition(to, cause string, now time.Time) {
+	if s.state == to {
+		return
+	}
+	change := model.StateTransition{From: s.state, To: to, Cause: cause}
+	s.state = to
+	s.event(model.EventTransition, fmt.Sprintf("%s → %s (%s).", change.From, change.To, cause), change, now)
+	log.Printf("[INFO][Generator][Sensor] %s %s → %s (%s).", s.vehicleID, change.From, change.To, cause)
+
+	// A vehicle going idle ends its trip, however it got there
+	if to == model.StateIdle {
+		s.closeTrip(cause, now)
+	}
+}
+
+// settle applies the automatic transitions due to the vehicle speed and health, and reports whether the state changed.
+func (s *simulator) settle(now time.Time) bool {
+	from := s.state
+
+	if cause := s.faultCause(); cause != "" && (s.canDrive() || s.state == model.StateBraking) {
+		s.transition(model.StateFault, cause, now)
+	}
+
+	if s.state == model.StateRunning && s.track.inBrakingZone() {
+		s.targetSpeed = 0
+		s.transition(model.StateBraking, "braking zone", now)
+	}
+
+	if s.speed <= 0 {
+		switch {
+		case s.state == model.StateBraking:
+			s.transition(model.StateIdle, "at rest", now)
+		case s.state == model.StateRunning && s.targetSpeed <= 0:
+			s.transition(model.StateReady, "at rest", now)
+		}
+	}
+	return s.state != from
+}
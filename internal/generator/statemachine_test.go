@@ -0,0 +1,145 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+func TestStateMachineTransitions(t *testing.T) {
+	var events []model.Event
+	sim := newSimulator(goldenVehicle, rand.New(rand.NewSource(1)), func(e model.Event) { events = append(events, e) })
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	interval := goldenVehicle.Sensor.Interval
+
+	// run steps the vehicle for d
+	run := func(d time.Duration) {
+		for end := now.Add(d); now.Before(end); {
+			now = now.Add(interval)
+			sim.step(now, interval)
+		}
+	}
+	// send handles cmd and reports whether it was rejected
+	send := func(action string, params interface{}) bool {
+		events = events[:0]
+		sim.handle(model.Command{Action: action, Params: params}, now)
+		return len(events) > 0 && events[0].Type == model.EventRejected
+	}
+	expect := func(state string) {
+		t.Helper()
+		if sim.state != state {
+			t.Fatalf("state = %s, want %s", sim.state, state)
+		}
+	}
+
+	if !send("accelerate", 20.0) {
+		t.Error("accelerate accepted while idle")
+	}
+	send("start", nil)
+	expect(model.StateReady)
+	if !send("start", nil) {
+		t.Error("start accepted while ready")
+	}
+
+	send("accelerate", 80.0)
+	expect(model.StateRunning)
+	run(30 * time.Second)
+	if !send("mode", "eco") {
+		t.Error("mode with a lower cap than the current speed accepted")
+	}
+
+	send("stop", nil)
+	expect(model.StateBraking)
+	run(30 * time.Second)
+	expect(model.StateIdle)
+
+	send("start", nil)
+	send("accelerate", 30.0)
+	run(5 * time.Second)
+	send("emergencyStop", nil)
+	expect(model.StateEmergencyStopped)
+	if !send("start", nil) {
+		t.Error("start accepted while emergency stopped")
+	}
+	if !send("reset", nil) {
+		t.Error("reset accepted while still moving")
+	}
+	run(10 * time.Second)
+	send("reset", nil)
+	expect(model.StateIdle)
+
+	send("start", nil)
+	send("accelerate", 30.0)
+	send("fault", map[string]interface{}{"type": "stuck", "channel": "speed"})
+	run(interval)
+	expect(model.StateFault)
+	run(10 * time.Second)
+	if !send("reset", nil) {
+		t.Error("reset accepted while the speed fault persists")
+	}
+	send("fault", map[string]interface{}{"type": "clear", "channel": "speed"})
+	send("reset", nil)
+	expect(model.StateIdle)
+}
+
+func TestPeriodicStateOnlyOnChange(t *testing.T) {
+	states := 0
+	sim := newSimulator(goldenVehicle, rand.New(rand.NewSource(1)), func(e model.Event) {
+		if e.Type == model.EventState {
+			states++
+		}
+	})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	interval := goldenVehicle.Sensor.Interval
+
+	// At rest, the periodic refresh has nothing new to publish
+	sim.publishState(now)
+	for i := 0; i < 3; i++ {
+		now = now.Add(stateInterval)
+		sim.refreshState(now)
+	}
+	if states != 1 {
+		t.Fatalf("state events at rest = %d, want only the first", states)
+	}
+
+	// While driving, every refresh publishes the new position and speed
+	sim.handle(model.Command{Action: "start"}, now)
+	sim.handle(model.Command{Action: "accelerate", Params: 40.0}, now)
+	states = 0
+	for i := 0; i < 3; i++ {
+		for end := now.Add(stateInterval); now.Before(end); {
+			now = now.Add(interval)
+			sim.step(now, interval)
+		}
+		sim.refreshState(now)
+	}
+	if states != 3 {
+		t.Fatalf("state events while driving = %d, want 3", states)
+	}
+}
+
+func TestMalformedParamsAreRejected(t *testing.T) {
+	tests := []model.Command{
+		{Action: "accelerate", Params: "20"},
+		{Action: "accelerate"},
+		{Action: "set_speed", Params: true},
+		{Action: "set_speed", Params: map[string]interface{}{"speed": 20.0}},
+		{Action: "mode", Params: 2.0},
+		{Action: "mode"},
+	}
+
+	for _, cmd := range tests {
+		var rejected bool
+		sim := newSimulator(goldenVehicle, rand.New(rand.NewSource(1)), func(e model.Event) {
+			rejected = rejected || e.Type == model.EventRejected
+		})
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		sim.handle(model.Command{Action: "start"}, now)
+		sim.handle(cmd, now)
+		if !rejected || sim.state != model.StateReady || sim.targetSpeed != 0 || sim.mode.Name != "normal" {
+			t.Errorf("%s %v: rejected %t, %s at %g km/h in %q, want rejected and unchanged", cmd.Action, cmd.Params, rejected, sim.state, sim.targetSpeed, sim.mode.Name)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package generator
+
+import (
+	"math"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+)
+
+/*
+thermal is a first-order model of the vehicle temperature.
+The traction power sets the steady-state temperature, ambientTemp + risePerKW · P,
+and the temperature approaches it exponentially:
+- with the heating time constant of the driving mode while it is below it,
+- with the cooling time constant of the driving mode while it is above it.
+*/
+type thermal struct {
+	cfg         config.Thermal
+	temperature float64 // °C
+}
+
+func newThermal(cfg config.Thermal) *thermal {
+	return &thermal{cfg: cfg, temperature: cfg.AmbientTemp}
+}
+
+// step heats the vehicle with power (W) for dt seconds in mode and returns its temperature.
+func (t *thermal) step(power float64, mode config.Mode, dt float64) float64 {
+	target := t.cfg.AmbientTemp + t.cfg.RisePerKW*math.Max(0, power)/1000
+
+	tau := mode.CoolingTau
+	if target > t.temperature {
+		tau = mode.HeatingTau
+	}
+
+	// Exact solution of dT/dt = (target - T) / tau over dt, so large steps never overshoot
+	if tau <= 0 {
+		t.temperature = target
+	} else {
+		t.temperature += (target - t.temperature) * (1 - math.Exp(-dt/tau))
+	}
+	return t.temperature
+}
@@ -0,0 +1,52 @@
+package generator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+)
+
+func TestThermalModel(t *testing.T) {
+	cfg := config.Thermal{AmbientTemp: 20, RisePerKW: 2}
+	mode := config.Mode{HeatingTau: 60, CoolingTau: 120}
+	const dt = 0.1
+	run := func(th *thermal, power, seconds float64) float64 {
+		for i := 0; i < int(math.Round(seconds/dt)); i++ {
+			th.step(power, mode, dt)
+		}
+		return th.temperature
+	}
+
+	// 10 kW heats toward ambient + 20 °C, covering 1 - 1/e of the gap within the heating time constant
+	th := newThermal(cfg)
+	if got, want := run(th, 10000, mode.HeatingTau), 20+20*(1-math.Exp(-1)); math.Abs(got-want) > 1e-6 {
+		t.Errorf("after one heating τ: %.4f °C, want %.4f", got, want)
+	}
+	if got := run(th, 10000, 10*mode.HeatingTau); math.Abs(got-40) > 0.01 {
+		t.Errorf("steady state at 10 kW: %.4f °C, want 40", got)
+	}
+
+	// Without power it cools back to ambient with the cooling time constant
+	hot := th.temperature
+	if got, want := run(th, 0, mode.CoolingTau), 20+(hot-20)*math.Exp(-1); math.Abs(got-want) > 1e-6 {
+		t.Errorf("after one cooling τ: %.4f °C, want %.4f", got, want)
+	}
+	if got := run(th, 0, 10*mode.CoolingTau); math.Abs(got-20) > 0.01 {
+		t.Errorf("after ten cooling τ: %.4f °C, want ambient 20", got)
+	}
+
+	// Braking (negative power) does not heat, and never cools below ambient
+	if got := run(th, -5000, 60); math.Abs(got-20) > 0.01 {
+		t.Errorf("braking at ambient: %.4f °C, want 20", got)
+	}
+
+	// A step longer than the time constant lands on the steady state without overshooting
+	th = newThermal(cfg)
+	if got := th.step(10000, mode, 10*mode.HeatingTau); got > 40 || got < 39.99 {
+		t.Errorf("one long step: %.4f °C, want just under 40", got)
+	}
+	if got := th.step(10000, config.Mode{}, dt); got != 40 {
+		t.Errorf("without a time constant: %.4f °C, want the steady state 40", got)
+	}
+}
@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"math"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+)
+
+// brakingMargin is how early, at the current speed, the braking curves aim to reach a lower limit.
+const brakingMargin = 500 * time.Millisecond
+
+/*
+track follows where the vehicle is on its configured Track and what speed it may run at there.
+The speed limit at a position is the lowest of:
+- the limit of the segment the vehicle is in.
+- the braking curve √(v² + 2·a·d) toward every segment ahead with limit v, d meters away,
+  so the vehicle slows down before entering it instead of inside it.
+- the braking curve toward the end of the track, where it has to be at rest.
+a is the planned deceleration of the track, or half the braking force of the vehicle when unset.
+The curves aim brakingMargin ahead, so the lag of the speed controller does not carry the vehicle over a limit.
+*/
+type track struct {
+	cfg          config.Track
+	deceleration float64 // m/s²
+	position     float64 // m from the start of the track
+}
+
+func newTrack(cfg config.Track, dyn config.Dynamics) *track {
+	deceleration := cfg.Deceleration
+	if deceleration <= 0 {
+		deceleration = 0.5 * dyn.MaxBrakeForce / dyn.Mass
+	}
+	return &track{cfg: cfg, deceleration: deceleration}
+}
+
+// closed reports whether the track has an end.
+func (t *track) closed() bool {
+	return t.cfg.Length > 0
+}
+
+/*
+advance moves the vehicle by a step of dt seconds at speed (km/h).
+On a closed track the position stops at its end, and advance reports whether the vehicle reached it.
+*/
+func (t *track) advance(speed, dt float64) bool {
+	t.position += speed * kmhToMs * dt
+	if t.closed() && t.position >= t.cfg.Length {
+		t.position = t.cfg.Length
+		return true
+	}
+	return false
+}
+
+// distanceToEnd returns the meters left to the end of the track, or 0 on an open track.
+func (t *track) distanceToEnd() float64 {
+	if !t.closed() {
+		return 0
+	}
+	return t.cfg.Length - t.position
+}
+
+// inBrakingZone reports whether the vehicle is in the final stretch where it has to brake to a stop.
+func (t *track) inBrakingZone() bool {
+	return t.closed() && t.distanceToEnd() <= t.cfg.BrakingZone
+}
+
+// segment returns the name of the segment the vehicle is in, or "" outside every segment.
+func (t *track) segment() string {
+	for _, seg := range t.cfg.Segments {
+		if t.position >= seg.Start && t.position < seg.End {
+			return seg.Name
+		}
+	}
+	return ""
+}
+
+// station returns the name of the last station the vehicle reached, or "" before the first one.
+func (t *track) station() string {
+	name := ""
+	for _, st := range t.cfg.Stations {
+		if st.Position > t.position {
+			break
+		}
+		name = st.Name
+	}
+	return name
+}
+
+// limit returns the speed limit (km/h) at the current position and speed (km/h), and false when nothing limits the speed.
+func (t *track) limit(speed float64) (float32, bool) {
+	limit := math.Inf(1)
+	for _, seg := range t.cfg.Segments {
+		switch {
+		case t.position >= seg.End:
+		case t.position >= seg.Start:
+			limit = math.Min(limit, float64(seg.SpeedLimit))
+		default:
+			limit = math.Min(limit, t.brakingCurve(float64(seg.SpeedLimit), seg.Start-t.position, speed))
+		}
+	}
+	if t.closed() {
+		limit = math.Min(limit, t.brakingCurve(0, t.distanceToEnd(), speed))
+	}
+
+	if math.IsInf(limit, 1) {
+		return 0, false
+	}
+	return float32(limit), true
+}
+
+/*
+brakingCurve returns the highest speed (km/h) from which the vehicle slows down to v (km/h) within distance meters,
+less the distance it covers at speed (km/h) during brakingMargin.
+*/
+func (t *track) brakingCurve(v, distance, speed float64) float64 {
+	distance = math.Max(0, distance-speed*kmhToMs*brakingMargin.Seconds())
+	ms := v * kmhToMs
+	return math.Sqrt(ms*ms+2*t.deceleration*distance) / kmhToMs
+}
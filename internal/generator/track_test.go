@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+func TestTrackLimitsAndEndBraking(t *testing.T) {
+	vehicle := goldenVehicle
+	vehicle.Sensor.Modes = []config.Mode{{
+		Name: "normal", SpeedCap: 0.8, GrowthFactor: 1, AccelerationLimit: 3, HeatingTau: 60, CoolingTau: 120,
+		PID: config.PID{Kp: 0.2, Ki: 0.05},
+	}}
+	vehicle.Sensor.Track = config.Track{
+		Length:      2000,
+		BrakingZone: 100,
+		Segments:    []config.Segment{{Name: "curve", Start: 800, End: 1100, SpeedLimit: 50}},
+	}
+	sim := newSimulator(vehicle, rand.New(rand.NewSource(1)), nil)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	interval := vehicle.Sensor.Interval
+
+	sim.handle(model.Command{Action: "start"}, now)
+	sim.handle(model.Command{Action: "set_speed", Params: 120.0}, now)
+
+	var peak, curvePeak float32
+	for i := 0; i < 30000 && sim.state != model.StateIdle; i++ {
+		now = now.Add(interval)
+		data, _ := sim.step(now, interval)
+		if data.Position+data.DistanceToEnd != 2000 {
+			t.Fatalf("position %.1f + distance to end %.1f, want the track length", data.Position, data.DistanceToEnd)
+		}
+		if sim.speed > peak {
+			peak = sim.speed
+		}
+		if sim.track.segment() == "curve" && sim.speed > curvePeak {
+			curvePeak = sim.speed
+		}
+	}
+
+	if peak < 110 {
+		t.Errorf("peak speed %.1f km/h, want the vehicle to reach its target outside the curve", peak)
+	}
+	if curvePeak > 51 {
+		t.Errorf("speed %.1f km/h in the curve, want it to brake to its 50 km/h limit before entering", curvePeak)
+	}
+	if sim.state != model.StateIdle {
+		t.Fatalf("state %s, want idle after braking at the end of the track", sim.state)
+	}
+	if d := sim.track.distanceToEnd(); d <= 0 || d > 100 {
+		t.Errorf("stopped %.1f m before the end, want within the braking zone", d)
+	}
+
+	// At the end of the track, driving on is rejected until the vehicle returns to the start
+	sim.handle(model.Command{Action: "start"}, now)
+	if sim.handle(model.Command{Action: "set_speed", Params: 50.0}, now); sim.state != model.StateReady {
+		t.Errorf("state %s after set_speed in the braking zone, want ready", sim.state)
+	}
+	sim.handle(model.Command{Action: "stop"}, now)
+	sim.handle(model.Command{Action: "reset"}, now)
+	if sim.track.position != 0 {
+		t.Errorf("position %.1f m after reset, want the start of the track", sim.track.position)
+	}
+}
@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+/*
+trip accumulates the TripSummary of the open trip of a simulator, from the readings it emits
+and the Commands it handles. Distance follows the true position, so sensor noise does not add up;
+the speeds, temperatures and pressures are the readings, leaving out NaN ones.
+A nil trip is no trip: every method does nothing.
+*/
+type trip struct {
+	summary      model.TripSummary
+	speedSum     float64
+	speeds       int     // valid speed readings
+	lastPosition float64 // m, of the last reading
+}
+
+// newTrip opens a trip of vehicleID at now, at position. Its ID is the vehicle and the start time.
+func newTrip(vehicleID string, now time.Time, position float64) *trip {
+	return &trip{
+		summary: model.TripSummary{
+			TripID:          vehicleID + "-" + now.Format("20060102T150405.000"),
+			VehicleID:       vehicleID,
+			StartedAt:       now,
+			Commands:        1, // the start
+			PeakTemperature: float32(math.Inf(-1)),
+			PeakPressure:    float32(math.Inf(-1)),
+		},
+		lastPosition: position,
+	}
+}
+
+// id returns the ID of the trip, or "" outside trips.
+func (t *trip) id() string {
+	if t == nil {
+		return ""
+	}
+	return t.summary.TripID
+}
+
+// add stamps a reading, taken at position, with the trip and accounts for it.
+func (t *trip) add(d *model.SensorData, position float64) {
+	if t == nil {
+		return
+	}
+	d.TripID = t.summary.TripID
+	t.summary.Samples++
+
+	// Returning to the start of the track is not travelled distance
+	if position > t.lastPosition {
+		t.summary.Distance += position - t.lastPosition
+	}
+	t.lastPosition = position
+
+	if isValid(d.Speed) {
+		t.speedSum += float64(d.Speed)
+		t.speeds++
+		if t.speeds == 1 || d.Speed > t.summary.MaxSpeed {
+			t.summary.MaxSpeed = d.Speed
+		}
+	}
+	if isValid(d.Temperature) && d.Temperature > t.summary.PeakTemperature {
+		t.summary.PeakTemperature = d.Temperature
+	}
+	if isValid(d.Pressure) && d.Pressure > t.summary.PeakPressure {
+		t.summary.PeakPressure = d.Pressure
+	}
+}
+
+// command accounts for a Command issued during the trip.
+func (t *trip) command(rejected bool) {
+	if t == nil {
+		return
+	}
+	t.summary.Commands++
+	if rejected {
+		t.summary.Rejected++
+	}
+}
+
+// modeChange accounts for a change of driving mode during the trip.
+func (t *trip) modeChange() {
+	if t == nil {
+		return
+	}
+	t.summary.ModeChanges++
+}
+
+// close ends the trip at now, by the Command or condition endedBy, and returns its summary.
+func (t *trip) close(endedBy string, now time.Time) model.TripSummary {
+	s := t.summary
+	s.EndedAt, s.EndedBy = now, endedBy
+	s.Duration = now.Sub(s.StartedAt).Seconds()
+	if t.speeds > 0 {
+		s.AverageSpeed = float32(t.speedSum / float64(t.speeds))
+	}
+	// A trip without valid readings has no peaks
+	if math.IsInf(float64(s.PeakTemperature), -1) {
+		s.PeakTemperature = 0
+	}
+	if math.IsInf(float64(s.PeakPressure), -1) {
+		s.PeakPressure = 0
+	}
+	return s
+}
+
+/*
+openTrip opens a trip on an accepted "start", publishing a "trip" Event.
+Its ID is stamped on every reading until the trip ends.
+*/
+func (s *simulator) openTrip(now time.Time) {
+	s.trip = newTrip(s.vehicleID, now, s.track.position)
+	message := fmt.Sprintf("Started trip %s.", s.trip.id())
+	s.event(model.EventTrip, message, nil, now)
+	log.Printf("[INFO][Generator][Sensor] %s %s", s.vehicleID, message)
+}
+
+/*
+closeTrip ends the open trip, if any, publishing its TripSummary in a "trip" Event.
+Trips end on an accepted "stop", or when the vehicle goes idle otherwise (e.g. "reset", or at rest after the braking zone).
+*/
+func (s *simulator) closeTrip(endedBy string, now time.Time) {
+	if s.trip == nil {
+		return
+	}
+	summary := s.trip.close(endedBy, now)
+	s.trip = nil
+
+	message := fmt.Sprintf("Finished trip %s (%s): %.0f m in %.1f s, max %.1f km/h, average %.1f km/h.",
+		summary.TripID, endedBy, summary.Distance, summary.Duration, summary.MaxSpeed, summary.AverageSpeed)
+	s.event(model.EventTrip, message, summary, now)
+	log.Printf("[INFO][Generator][Sensor] %s %s", s.vehicleID, message)
+}
@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+func TestTripSummary(t *testing.T) {
+	vehicle := goldenVehicle
+	vehicle.Sensor.Modes = append([]config.Mode{{Name: "eco", SpeedCap: 0.5, GrowthFactor: 0.7, HeatingTau: 90, CoolingTau: 120}}, goldenVehicle.Sensor.Modes...)
+
+	var summaries []model.TripSummary
+	sim := newSimulator(vehicle, rand.New(rand.NewSource(1)), func(e model.Event) {
+		if summary, ok := e.Payload.(model.TripSummary); ok && e.Type == model.EventTrip {
+			summaries = append(summaries, summary)
+		}
+	})
+
+	timeline := map[int]model.Command{
+		10:  {Action: "start"},
+		11:  {Action: "accelerate", Params: 40.0},
+		12:  {Action: "start"}, // rejected, already running
+		50:  {Action: "mode", Params: "eco"},
+		110: {Action: "stop"},
+		500: {Action: "start"},
+	}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	interval := vehicle.Sensor.Interval
+	var trips []string
+	for tick := 1; tick <= 510; tick++ {
+		now := start.Add(time.Duration(tick) * interval)
+		if cmd, ok := timeline[tick]; ok {
+			sim.handle(cmd, now)
+		}
+		data, ok := sim.step(now, interval)
+		if ok && (len(trips) == 0 || trips[len(trips)-1] != data.TripID) {
+			trips = append(trips, data.TripID)
+		}
+	}
+
+	// Readings are stamped only during trips, with a new ID for every trip
+	if len(trips) != 4 || trips[0] != "" || trips[1] == "" || trips[2] != "" || trips[3] == "" || trips[1] == trips[3] {
+		t.Fatalf("trip IDs of the readings = %q, want none, a trip, none and another trip", trips)
+	}
+
+	if len(summaries) != 1 {
+		t.Fatalf("trip summaries = %d, want 1", len(summaries))
+	}
+	s := summaries[0]
+	if s.TripID != trips[1] || s.VehicleID != "golden" || s.EndedBy != "stop" {
+		t.Errorf("summary = %+v, want trip %s of golden ended by stop", s, trips[1])
+	}
+	if s.Duration != 1 || s.Samples != 100 {
+		t.Errorf("duration = %gs over %d samples, want 1s over 100", s.Duration, s.Samples)
+	}
+	if s.Commands != 5 || s.Rejected != 1 || s.ModeChanges != 1 {
+		t.Errorf("commands = %d (%d rejected), mode changes = %d, want 5 (1 rejected) and 1", s.Commands, s.Rejected, s.ModeChanges)
+	}
+	if s.Distance <= 0 || s.MaxSpeed <= 0 || s.AverageSpeed <= 0 || s.AverageSpeed >= s.MaxSpeed {
+		t.Errorf("distance %g m, max %g km/h, average %g km/h, want a drive", s.Distance, s.MaxSpeed, s.AverageSpeed)
+	}
+	if float64(s.PeakTemperature) < vehicle.Sensor.Thermal.AmbientTemp-1 || s.PeakPressure <= 0 {
+		t.Errorf("peak temperature %g, peak pressure %g, want readings", s.PeakTemperature, s.PeakPressure)
+	}
+}
@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+// window aggregates SensorData into ResultData alongside the batches of Process.
+type window interface {
+	// add takes the next reading and returns the results of the windows it closed, if any.
+	add(data model.SensorData) []model.ResultData
+}
+
+/*
+newWindow builds the window declared by cfg, which config.LoadConfig already validated.
+Its results are assessed against sensor, like the batches of Process.
+*/
+func newWindow(cfg config.WindowConfig, percentiles []float64, sensor config.SensorConfig) window {
+	switch cfg.Type {
+	case config.WindowCount:
+		return &countWindow{name: cfg.Name, size: cfg.Count, hop: cfg.HopCount, percentiles: percentiles, sensor: sensor}
+	case config.WindowHopping:
+		// Hopping windows start every hop, so they end size after it
+		return &timeWindow{name: cfg.Name, size: cfg.Size, hop: cfg.Hop, offset: cfg.Size % cfg.Hop, percentiles: percentiles, sensor: sensor}
+	default:
+		// Tumbling and sliding windows end every hop
+		return &timeWindow{name: cfg.Name, size: cfg.Size, hop: cfg.Hop, percentiles: percentiles, sensor: sensor}
+	}
+}
+
+/*
+timeWindow closes a window of size every hop, by the CreatedAt of the readings (event time).
+Window ends are aligned to multiples of hop since the zero time, shifted by offset,
+and a window covers the readings taken in [end - size, end).
+- hop == size: tumbling, every reading is in one window.
+- hop < size: sliding, windows overlap and a reading is in several of them.
+- hop > size: hopping with gaps, readings between windows are left out.
+Like the batches of Process, a window without readings emits nothing.
+*/
+type timeWindow struct {
+	name        string
+	size, hop   time.Duration
+	offset      time.Duration
+	percentiles []float64
+	sensor      config.SensorConfig
+	end         time.Time          // end of the next window to close
+	data        []model.SensorData // readings of the windows still open, in arrival order
+	agg         aggregator         // reused to summarize each window
+}
+
+// nextEnd returns the first window end after t.
+func (w *timeWindow) nextEnd(t time.Time) time.Time {
+	return t.Add(-w.offset).Truncate(w.hop).Add(w.hop + w.offset)
+}
+
+func (w *timeWindow) add(data model.SensorData) []model.ResultData {
+	var results []model.ResultData
+	if w.end.IsZero() {
+		w.end = w.nextEnd(data.CreatedAt)
+	}
+
+	for !data.CreatedAt.Before(w.end) {
+		start := w.end.Add(-w.size)
+		w.agg.reset()
+		for _, d := range w.data {
+			if !d.CreatedAt.Before(start) && d.CreatedAt.Before(w.end) {
+				w.agg.add(d, w.sensor)
+			}
+		}
+		if w.agg.count > 0 {
+			result := w.agg.result(w.end, w.percentiles, expectedReadings(w.size, w.sensor))
+			result.Window = w.name
+			results = append(results, result)
+		}
+
+		// Readings before the start of the next window are no longer needed
+		w.end = w.end.Add(w.hop)
+		kept := w.data[:0]
+		for _, d := range w.data {
+			if !d.CreatedAt.Before(w.end.Add(-w.size)) {
+				kept = append(kept, d)
+			}
+		}
+		w.data = kept
+
+		// Skip the empty windows of a gap in the readings at once
+		if len(w.data) == 0 {
+			w.end = w.nextEnd(data.CreatedAt)
+		}
+	}
+
+	w.data = append(w.data, data)
+	return results
+}
+
+/*
+countWindow emits the statistics of the last size readings every hop readings,
+once it has seen size readings. Its results are stamped with the CreatedAt of their latest reading.
+*/
+type countWindow struct {
+	name        string
+	size, hop   int
+	percentiles []float64
+	sensor      config.SensorConfig
+	data        []model.SensorData // ring buffer of the last size readings, the oldest at next once full
+	next        int                // index the next reading is written at
+	since       int                // readings since the last emission
+	agg         aggregator         // reused to summarize each window
+}
+
+func (w *countWindow) add(data model.SensorData) []model.ResultData {
+	if len(w.data) < w.size {
+		w.data = append(w.data, data)
+	} else {
+		w.data[w.next] = data
+	}
+	w.next = (w.next + 1) % w.size
+	w.since++
+
+	if len(w.data) < w.size || w.since < w.hop {
+		return nil
+	}
+	w.since = 0
+	w.agg.reset()
+	for i := range w.data {
+		w.agg.add(w.data[(w.next+i)%w.size], w.sensor)
+	}
+	result := w.agg.result(data.CreatedAt, w.percentiles, float32(w.size))
+	result.Window = w.name
+	return []model.ResultData{result}
+}
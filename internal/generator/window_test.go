@@ -0,0 +1,70 @@
+package generator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+// runWindow feeds a reading every 10ms for 1s, with speed counting them, and returns the results of w.
+func runWindow(w window) []model.ResultData {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var results []model.ResultData
+	for i := 0; i < 100; i++ {
+		results = append(results, w.add(model.SensorData{Speed: float32(i), CreatedAt: start.Add(time.Duration(i) * 10 * time.Millisecond)})...)
+	}
+	return results
+}
+
+func TestWindows(t *testing.T) {
+	tests := []struct {
+		cfg config.WindowConfig
+		// counts and last speed of every result
+		counts []int
+		lasts  []float32
+	}{
+		{
+			cfg:    config.WindowConfig{Name: "w", Type: config.WindowTumbling, Size: 250 * time.Millisecond, Hop: 250 * time.Millisecond},
+			counts: []int{25, 25, 25},
+			lasts:  []float32{24, 49, 74},
+		},
+		{
+			// Every 200ms the last 400ms, the first windows still filling up
+			cfg:    config.WindowConfig{Name: "w", Type: config.WindowSliding, Size: 400 * time.Millisecond, Hop: 200 * time.Millisecond},
+			counts: []int{20, 40, 40, 40},
+			lasts:  []float32{19, 39, 59, 79},
+		},
+		{
+			// A 100ms window starting every 300ms
+			cfg:    config.WindowConfig{Name: "w", Type: config.WindowHopping, Size: 100 * time.Millisecond, Hop: 300 * time.Millisecond},
+			counts: []int{10, 10, 10},
+			lasts:  []float32{9, 39, 69},
+		},
+		{
+			// Every 30 readings the last 40, once 40 were seen
+			cfg:    config.WindowConfig{Name: "w", Type: config.WindowCount, Count: 40, HopCount: 30},
+			counts: []int{40, 40, 40},
+			lasts:  []float32{39, 69, 99},
+		},
+	}
+
+	for _, tt := range tests {
+		results := runWindow(newWindow(tt.cfg, nil, config.SensorConfig{}))
+		if len(results) != len(tt.counts) {
+			t.Errorf("%s: %d results, want %d", tt.cfg.Type, len(results), len(tt.counts))
+			continue
+		}
+		for i, r := range results {
+			if r.Count != tt.counts[i] || r.Stats["speed"].Last != tt.lasts[i] || r.Window != "w" {
+				t.Errorf("%s: result %d has %d readings up to %v in window %q, want %d up to %v in w",
+					tt.cfg.Type, i, r.Count, r.Stats["speed"].Last, r.Window, tt.counts[i], tt.lasts[i])
+			}
+			// The readings are aggregated in order, also once the count window wrapped around
+			if first := tt.lasts[i] - float32(tt.counts[i]-1); r.Stats["speed"].First != first {
+				t.Errorf("%s: result %d starts at %v, want %v", tt.cfg.Type, i, r.Stats["speed"].First, first)
+			}
+		}
+	}
+}
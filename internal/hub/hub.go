@@ -1,24 +1,60 @@
 package hub
 
 import (
-	"log"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+
 	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/clock"
 	"github.com/vasyl-ks/TM-software-H11/internal/model"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
 )
 
 /*
 Hub acts as a central bridge between the Generator, Frontend, and Consumer.
-- Generator ↔ Hub: exchanges ResultData and Command via internal channels.
-- Frontend ↔ Hub: exchanges Command and ResultData over WebSocket.
-- Consumer ↔ Hub: sends ResultData via UDP and Command via TCP.
+- Generator ↔ Hub: exchanges ResultData, Event, raw SensorFrame and Command via internal channels.
+- Frontend ↔ Hub: receives Command over WebSocket; clients connecting to /api/stream?raw=true also receive the raw SensorFrames.
+- Sinks: every ResultData, Command, Event and SensorFrame is fanned out to the outputs declared in config.Hub.Sinks
+  (by default ResultData via UDP and Command, Event and SensorFrame via TCP to the Consumer, and all but Command via WS to the Frontend).
+- /api/state serves the latest VehicleState of every vehicle, including its active faults.
+- /api/modes serves the driving modes of every vehicle.
+- Scheduler: queues Commands on /api/schedule and dispatches them like Frontend Commands when due.
+- /api/sinks reports the queue and error accounting of every Sink.
+- /api/queues reports the depth, drops and stalls of every pipeline queue.
+- Clock: "pause", "resume", "step" and "speed" Commands drive the simulated clock, served on /api/clock.
 */
-func Run(inResultChan <-chan model.ResultData, outCommandChan chan<- model.Command) {
+func Run(inResultChan <-chan model.ResultData, inEventChan <-chan model.Event, inFrameChan <-chan model.SensorFrame, outCommandQueue *queue.Queue[model.Command]) {
 	defer log.Println("[INFO][Hub] Running.")
 
-	// Create unbuffered channel.
-	internalCommandChan := make(chan model.Command)
+	// Create bounded queue.
+	commandQueue := queue.New[model.Command]("hub.command")
+	http.HandleFunc("/api/queues", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, queue.Snapshot())
+	})
+
+	// Sinks
+	sinks := startSinks(config.Hub.Sinks)
+	http.HandleFunc("/api/sinks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, sinks.stats())
+	})
+
+	// Vehicle state
+	states := newStateStore()
+	http.Handle("/api/state", states)
+	http.HandleFunc("/api/modes", serveModes)
+
+	// Clock
+	http.HandleFunc("/api/clock", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, clock.Default.State())
+	})
+
+	// Scheduler
+	scheduler := NewScheduler(func(cmd model.Command) {
+		commandQueue.Push(cmd)
+	})
+	http.Handle("/api/schedule", scheduler)
 
 	// WS
 	http.HandleFunc("/api/stream", func(w http.ResponseWriter, r *http.Request) {
@@ -29,34 +65,47 @@ func Run(inResultChan <-chan model.ResultData, outCommandChan chan<- model.Comma
 		}
 
 		// Launch concurrent goroutines
-		go ReceiveCommandFromFrontEnd(conn, internalCommandChan, outCommandChan)
-		go SendResultToFrontEnd(conn, inResultChan)
+		raw, _ := strconv.ParseBool(r.URL.Query().Get("raw"))
+		results, frames := wsClients.add(conn, raw)
+		go func() {
+			ReceiveCommandFromFrontEnd(conn, commandQueue)
+			wsClients.remove(conn)
+		}()
+		go SendToFrontEnd(conn, results, frames)
 	})
 	go func() {
 		http.ListenAndServe("127.0.0.1:"+fmt.Sprintf("%d", config.Hub.WSPort), nil)
 	}()
 
-	// UDP
-	{
-		// Create Connection
-		conn := CreateConnUDP()
-		if conn == nil {
-			return
+	// Fan out ResultData to every Sink
+	go func() {
+		for result := range inResultChan {
+			sinks.sendResult(result)
 		}
+	}()
 
-		// Launch concurrent goroutines
-		go SendResultToConsumer(conn, inResultChan)
-	}
+	// Record vehicle states and fan out Events to every Sink
+	go func() {
+		for event := range inEventChan {
+			states.update(event)
+			sinks.sendEvent(event)
+		}
+	}()
 
-	// TCP
-	{
-		// Create Connection
-		conn := CreateConnTCP()
-		if conn == nil {
-			return
+	// Fan out raw SensorFrames to every Sink
+	go func() {
+		for frame := range inFrameChan {
+			sinks.sendFrame(frame)
 		}
+	}()
 
-		// Launch concurrent goroutines
-		go SendCommandToConsumer(conn, internalCommandChan)
-	}
+	// Apply clock Commands, route the others to the Generator, and log both through every Sink
+	go func() {
+		for cmd := range commandQueue.Out() {
+			if !clock.Handle(cmd) {
+				outCommandQueue.Push(cmd)
+			}
+			sinks.sendCommand(cmd)
+		}
+	}()
 }
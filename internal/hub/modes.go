@@ -0,0 +1,28 @@
+package hub
+
+import (
+	"net/http"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+)
+
+// vehicleModes lists the driving modes a vehicle accepts through the "mode" command.
+type vehicleModes struct {
+	VehicleID   string        `json:"vehicleID"`
+	DefaultMode string        `json:"defaultMode"`
+	Modes       []config.Mode `json:"modes"`
+}
+
+// serveModes exposes the driving modes of every configured vehicle on /api/modes.
+func serveModes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	list := make([]vehicleModes, 0, len(config.Vehicles))
+	for _, v := range config.Vehicles {
+		list = append(list, vehicleModes{VehicleID: v.VehicleID, DefaultMode: v.Sensor.DefaultMode, Modes: v.Sensor.Modes})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
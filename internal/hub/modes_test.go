@@ -0,0 +1,39 @@
+package hub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+)
+
+func TestServeModes(t *testing.T) {
+	defer func(vehicles []config.VehicleConfig) { config.Vehicles = vehicles }(config.Vehicles)
+	eco := config.Mode{Name: "eco", SpeedCap: 0.5, GrowthFactor: 0.7, AccelerationLimit: 1}
+	sport := config.Mode{Name: "sport", SpeedCap: 1, GrowthFactor: 1.3}
+	config.Vehicles = []config.VehicleConfig{
+		{VehicleID: "123", Sensor: config.SensorConfig{Modes: []config.Mode{eco, sport}, DefaultMode: "sport"}},
+		{VehicleID: "124", Sensor: config.SensorConfig{Modes: []config.Mode{eco}, DefaultMode: "eco"}},
+	}
+
+	w := httptest.NewRecorder()
+	serveModes(w, httptest.NewRequest(http.MethodGet, "/api/modes", nil))
+	var list []vehicleModes
+	if err := json.Unmarshal(w.Body.Bytes(), &list); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("GET /api/modes = %d %s", w.Code, w.Body)
+	}
+	if len(list) != 2 || list[0].VehicleID != "123" || list[0].DefaultMode != "sport" || len(list[0].Modes) != 2 || list[1].DefaultMode != "eco" {
+		t.Fatalf("modes = %+v, want the modes and default of both vehicles", list)
+	}
+	if got := list[0].Modes[0]; got != eco {
+		t.Errorf("first mode of 123 = %+v, want %+v", got, eco)
+	}
+
+	w = httptest.NewRecorder()
+	serveModes(w, httptest.NewRequest(http.MethodPost, "/api/modes", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /api/modes = %d, want 405", w.Code)
+	}
+}
@@ -0,0 +1,279 @@
+package hub
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/internal/clock"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+// scheduleRequest is the JSON body accepted by POST /api/schedule.
+type scheduleRequest struct {
+	Action    string      `json:"action"`
+	Params    interface{} `json:"params,omitempty"`
+	VehicleID string      `json:"vehicleID,omitempty"` // empty addresses every vehicle
+	At        string      `json:"at,omitempty"`        // "T+5s", "14:32:00" or RFC3339
+	Delay     string      `json:"delay,omitempty"`     // "5s", "1m30s"
+}
+
+// scheduleHistory is how many executed or cancelled ScheduledCommands the Scheduler keeps listing.
+const scheduleHistory = 100
+
+// scheduledEntry couples a ScheduledCommand with the timer that fires it.
+type scheduledEntry struct {
+	cmd   model.ScheduledCommand
+	timer *clock.Timer
+}
+
+/*
+Scheduler keeps Commands queued for delayed execution.
+When a Command is due, it is dispatched through the same path as a Command
+received from the Frontend, stamped with its ScheduleID so the Consumer logs it as scheduled.
+Only the latest scheduleHistory executed or cancelled entries are kept, so a long run does not grow the list forever.
+*/
+type Scheduler struct {
+	mu       sync.Mutex
+	nextID   int
+	entries  map[string]*scheduledEntry
+	finished []string // IDs of executed and cancelled entries, oldest first
+	dispatch func(model.Command)
+}
+
+// NewScheduler returns an empty Scheduler that hands due Commands to dispatch.
+func NewScheduler(dispatch func(model.Command)) *Scheduler {
+	return &Scheduler{
+		entries:  make(map[string]*scheduledEntry),
+		dispatch: dispatch,
+	}
+}
+
+// Enqueue schedules cmd to be dispatched at executeAt and returns the created entry.
+func (s *Scheduler) Enqueue(cmd model.Command, executeAt time.Time) model.ScheduledCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	entry := &scheduledEntry{
+		cmd: model.ScheduledCommand{
+			ID:        id,
+			Command:   cmd,
+			ExecuteAt: executeAt,
+			Status:    model.SchedulePending,
+			CreatedAt: clock.Now(),
+		},
+	}
+	entry.timer = clock.AfterFunc(clock.Until(executeAt), func() { s.execute(id) })
+	s.entries[id] = entry
+
+	log.Printf("[INFO][Hub][Scheduler] Scheduled #%s: %s at %s.", id, cmd.Action, executeAt.Format("15:04:05.000"))
+	return entry.cmd
+}
+
+// List returns every known ScheduledCommand ordered by execution time.
+func (s *Scheduler) List() []model.ScheduledCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]model.ScheduledCommand, 0, len(s.entries))
+	for _, entry := range s.entries {
+		list = append(list, entry.cmd)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ExecuteAt.Before(list[j].ExecuteAt) })
+	return list
+}
+
+// Cancel stops a pending ScheduledCommand so it is never dispatched. An entry no longer pending is returned with the error.
+func (s *Scheduler) Cancel(id string) (model.ScheduledCommand, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[id]
+	if !ok {
+		return model.ScheduledCommand{}, fmt.Errorf("scheduled command #%s not found", id)
+	}
+	if entry.cmd.Status != model.SchedulePending {
+		return entry.cmd, fmt.Errorf("scheduled command #%s is already %s", id, entry.cmd.Status)
+	}
+	entry.timer.Stop()
+	entry.cmd.Status = model.ScheduleCancelled
+	s.finish(id)
+
+	log.Printf("[INFO][Hub][Scheduler] Cancelled #%s.", id)
+	return entry.cmd, nil
+}
+
+// execute marks a pending entry as executed and dispatches its Command.
+func (s *Scheduler) execute(id string) {
+	s.mu.Lock()
+	entry, ok := s.entries[id]
+	if !ok || entry.cmd.Status != model.SchedulePending {
+		s.mu.Unlock()
+		return
+	}
+	now := clock.Now()
+	entry.cmd.Status = model.ScheduleExecuted
+	entry.cmd.ExecutedAt = &now
+	cmd := entry.cmd.Command
+	cmd.ScheduleID = id
+	s.finish(id)
+	s.mu.Unlock()
+
+	log.Printf("[INFO][Hub][Scheduler] Executing #%s: %s.", id, cmd.Action)
+	s.dispatch(cmd)
+}
+
+// finish records that entry id is no longer pending, forgetting the oldest finished entries past scheduleHistory. Call it with mu held.
+func (s *Scheduler) finish(id string) {
+	s.finished = append(s.finished, id)
+	for len(s.finished) > scheduleHistory {
+		delete(s.entries, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+/*
+ServeHTTP exposes the Scheduler on /api/schedule.
+- GET lists every ScheduledCommand.
+- POST enqueues one request object or an array of them.
+- DELETE ?id=N cancels a pending ScheduledCommand.
+*/
+func (s *Scheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.List())
+
+	case http.MethodPost:
+		reqs, err := decodeScheduleRequests(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Validate every request before enqueuing any of them
+		now := clock.Now()
+		times := make([]time.Time, len(reqs))
+		for i, req := range reqs {
+			if req.Action == "" {
+				http.Error(w, fmt.Sprintf("request %d: action is required", i), http.StatusBadRequest)
+				return
+			}
+			if times[i], err = parseExecuteAt(req, now); err != nil {
+				http.Error(w, fmt.Sprintf("request %d: %v", i, err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		created := make([]model.ScheduledCommand, len(reqs))
+		for i, req := range reqs {
+			created[i] = s.Enqueue(model.Command{Action: req.Action, Params: req.Params, VehicleID: req.VehicleID}, times[i])
+		}
+		writeJSON(w, http.StatusCreated, created)
+
+	case http.MethodDelete:
+		cmd, err := s.Cancel(r.URL.Query().Get("id"))
+		if err != nil {
+			// An entry that already ran or was cancelled is still known, unlike an unknown ID
+			status := http.StatusNotFound
+			if cmd.ID != "" {
+				status = http.StatusConflict
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		writeJSON(w, http.StatusOK, cmd)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeScheduleRequests parses a single scheduleRequest or an array of them.
+func decodeScheduleRequests(r *http.Request) ([]scheduleRequest, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var reqs []scheduleRequest
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(raw, &reqs); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return reqs, nil
+	}
+
+	var req scheduleRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return append(reqs, req), nil
+}
+
+/*
+parseExecuteAt resolves when a scheduleRequest must run, relative to now.
+- delay: Go duration such as "5s" or "1m30s".
+- at: "T+<duration>", a wall-clock time of today ("14:32:00" or "14:32"), or an RFC3339 timestamp.
+*/
+func parseExecuteAt(req scheduleRequest, now time.Time) (time.Time, error) {
+	if req.Delay != "" {
+		d, err := time.ParseDuration(req.Delay)
+		if err != nil || d < 0 {
+			return time.Time{}, fmt.Errorf("invalid delay %q", req.Delay)
+		}
+		return now.Add(d), nil
+	}
+
+	at := strings.TrimSpace(req.At)
+	if at == "" {
+		return time.Time{}, errors.New("either at or delay is required")
+	}
+
+	// Relative time: T+5s
+	if strings.HasPrefix(strings.ToUpper(at), "T+") {
+		d, err := time.ParseDuration(at[2:])
+		if err != nil || d < 0 {
+			return time.Time{}, fmt.Errorf("invalid relative time %q", at)
+		}
+		return now.Add(d), nil
+	}
+
+	// Absolute timestamp
+	if t, err := time.Parse(time.RFC3339, at); err == nil {
+		return t, nil
+	}
+
+	// Wall-clock time of today
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		t, err := time.ParseInLocation(layout, at, now.Location())
+		if err != nil {
+			continue
+		}
+		t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, now.Location())
+		if t.Before(now) {
+			return time.Time{}, fmt.Errorf("time %q has already passed today", at)
+		}
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q", at)
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("[ERROR][Hub][HTTP] Error encoding JSON response:", err)
+	}
+}
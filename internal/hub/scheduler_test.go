@@ -0,0 +1,137 @@
+package hub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/internal/clock"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+func TestParseExecuteAt(t *testing.T) {
+	now := time.Date(2025, 1, 1, 14, 0, 0, 0, time.UTC)
+	tests := []struct {
+		req  scheduleRequest
+		want time.Time
+		err  bool
+	}{
+		{req: scheduleRequest{Delay: "1m30s"}, want: now.Add(90 * time.Second)},
+		{req: scheduleRequest{Delay: "-1s"}, err: true},
+		{req: scheduleRequest{Delay: "soon"}, err: true},
+		{req: scheduleRequest{At: "T+5s"}, want: now.Add(5 * time.Second)},
+		{req: scheduleRequest{At: "t+500ms"}, want: now.Add(500 * time.Millisecond)},
+		{req: scheduleRequest{At: "T+-5s"}, err: true},
+		{req: scheduleRequest{At: "14:32:00"}, want: now.Add(32 * time.Minute)},
+		{req: scheduleRequest{At: "15:00"}, want: now.Add(time.Hour)},
+		{req: scheduleRequest{At: "13:59:59"}, err: true}, // already passed today
+		{req: scheduleRequest{At: "2025-01-02T08:00:00Z"}, want: time.Date(2025, 1, 2, 8, 0, 0, 0, time.UTC)},
+		{req: scheduleRequest{At: "tomorrow"}, err: true},
+		{req: scheduleRequest{}, err: true},
+		{req: scheduleRequest{At: "T+1h", Delay: "5s"}, want: now.Add(5 * time.Second)}, // delay wins
+	}
+
+	for _, tt := range tests {
+		got, err := parseExecuteAt(tt.req, now)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseExecuteAt(%+v) = %s, want an error", tt.req, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseExecuteAt(%+v) = %s, %v, want %s", tt.req, got, err, tt.want)
+		}
+	}
+}
+
+func TestSchedulerHTTP(t *testing.T) {
+	defer func(c *clock.Clock) { clock.Default = c }(clock.Default)
+	clock.Default = clock.New(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), 1)
+	clock.Default.Pause()
+
+	var mu sync.Mutex
+	var dispatched []model.Command
+	s := NewScheduler(func(cmd model.Command) {
+		mu.Lock()
+		defer mu.Unlock()
+		dispatched = append(dispatched, cmd)
+	})
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	// An invalid request in an array enqueues none of them
+	if w := serve(http.MethodPost, "/api/schedule", `[{"action": "start", "delay": "1s"}, {"action": "stop"}]`); w.Code != http.StatusBadRequest {
+		t.Fatalf("POST without a time = %d, want 400", w.Code)
+	}
+	if w := serve(http.MethodPost, "/api/schedule", `{"delay": "1s"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("POST without an action = %d, want 400", w.Code)
+	}
+
+	w := serve(http.MethodPost, "/api/schedule", `[{"action": "accelerate", "params": 20, "at": "T+5s"}, {"action": "stop", "delay": "10s", "vehicleID": "123"}]`)
+	var created []model.ScheduledCommand
+	if err := json.Unmarshal(w.Body.Bytes(), &created); w.Code != http.StatusCreated || err != nil || len(created) != 2 {
+		t.Fatalf("POST = %d %s, want 2 created commands", w.Code, w.Body)
+	}
+
+	if w := serve(http.MethodDelete, "/api/schedule?id="+created[1].ID, ""); w.Code != http.StatusOK {
+		t.Fatalf("DELETE #%s = %d, want 200", created[1].ID, w.Code)
+	}
+	if w := serve(http.MethodDelete, "/api/schedule?id="+created[1].ID, ""); w.Code != http.StatusConflict {
+		t.Fatalf("DELETE of a cancelled command = %d, want 409", w.Code)
+	}
+	if w := serve(http.MethodDelete, "/api/schedule?id=unknown", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("DELETE of an unknown command = %d, want 404", w.Code)
+	}
+	if w := serve(http.MethodPut, "/api/schedule", ""); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("PUT = %d, want 405", w.Code)
+	}
+
+	// Only the pending command fires, stamped with its schedule ID
+	clock.Default.Step(time.Minute)
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(dispatched) == 1
+	})
+	if w := serve(http.MethodDelete, "/api/schedule?id="+created[0].ID, ""); w.Code != http.StatusConflict {
+		t.Errorf("DELETE of an executed command = %d, want 409", w.Code)
+	}
+	if cmd := dispatched[0]; cmd.Action != "accelerate" || cmd.Params != 20.0 || cmd.ScheduleID != created[0].ID {
+		t.Errorf("dispatched %+v, want accelerate 20 of #%s", cmd, created[0].ID)
+	}
+
+	var list []model.ScheduledCommand
+	w = serve(http.MethodGet, "/api/schedule", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list) != 2 {
+		t.Fatalf("GET = %s, want both commands", w.Body)
+	}
+	if list[0].Status != model.ScheduleExecuted || list[0].ExecutedAt == nil || list[1].Status != model.ScheduleCancelled {
+		t.Errorf("statuses = %s and %s, want executed and cancelled", list[0].Status, list[1].Status)
+	}
+
+	// Finished commands are forgotten past the history, pending ones are kept
+	for i := 0; i < scheduleHistory; i++ {
+		cmd := s.Enqueue(model.Command{Action: "stop"}, clock.Now().Add(time.Hour))
+		if _, err := s.Cancel(cmd.ID); err != nil {
+			t.Fatalf("Cancel(#%s): %v", cmd.ID, err)
+		}
+	}
+	pending := s.Enqueue(model.Command{Action: "start"}, clock.Now().Add(2*time.Hour))
+	list = s.List()
+	if len(list) != scheduleHistory+1 || list[len(list)-1].ID != pending.ID {
+		t.Errorf("listed %d commands, want the last %d finished and the pending one", len(list), scheduleHistory)
+	}
+	for _, cmd := range list {
+		if cmd.ID == created[0].ID || cmd.ID == created[1].ID {
+			t.Errorf("#%s still listed past the history", cmd.ID)
+		}
+	}
+}
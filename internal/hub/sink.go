@@ -0,0 +1,237 @@
+package hub
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+const defaultSinkQueueSize = 256
+
+/*
+Sink is an output of the Hub.
+Every Sink receives every ResultData, Command, Event and raw SensorFrame; a Sink that does not care
+about one of them simply returns nil.
+*/
+type Sink interface {
+	Name() string
+	SendResult(model.ResultData) error
+	SendCommand(model.Command) error
+	SendEvent(model.Event) error
+	SendFrame(model.SensorFrame) error
+	Close() error
+}
+
+// dropCounter is implemented by Sinks that may drop messages after accepting them, e.g. for slow clients.
+type dropCounter interface {
+	Dropped() uint64
+}
+
+// SinkFactory builds a Sink from its configuration.
+type SinkFactory func(cfg config.SinkConfig) (Sink, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]SinkFactory)
+)
+
+// RegisterSink makes a Sink type available to the "sinks" section of the config.
+func RegisterSink(kind string, factory SinkFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[kind] = factory
+}
+
+// NewSink builds the Sink declared by cfg using the registered factory of its type.
+func NewSink(cfg config.SinkConfig) (Sink, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[cfg.Type]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown sink type %q", cfg.Type)
+	}
+	return factory(cfg)
+}
+
+func init() {
+	RegisterSink("udp", newUDPSink)
+	RegisterSink("tcp", newTCPSink)
+	RegisterSink("ws", newWSSink)
+	RegisterSink("file", newFileSink)
+	RegisterSink("webhook", newWebhookSink)
+	RegisterSink("memory", newMemorySink)
+}
+
+// SinkStats reports the queue and error accounting of a running Sink.
+type SinkStats struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Queued    int    `json:"queued"`
+	Capacity  int    `json:"capacity"`
+	Sent      uint64 `json:"sent"`
+	Failed    uint64 `json:"failed"`
+	Dropped   uint64 `json:"dropped"`
+	LastError string `json:"lastError,omitempty"`
+}
+
+// sinkMessage carries exactly one of its fields to a sinkRunner.
+type sinkMessage struct {
+	result  *model.ResultData
+	command *model.Command
+	event   *model.Event
+	frame   *model.SensorFrame
+}
+
+/*
+sinkRunner owns a Sink and feeds it from its own queue,
+so a slow or failing output never stalls the Hub or the other outputs.
+- When the queue is full, the message is dropped and counted.
+- Send errors are counted and the last one is kept for SinkStats.
+*/
+type sinkRunner struct {
+	sink    Sink
+	kind    string
+	queue   chan sinkMessage
+	sent    atomic.Uint64
+	failed  atomic.Uint64
+	dropped atomic.Uint64
+	lastErr atomic.Value // string
+}
+
+// startSink wraps sink in a sinkRunner and launches its goroutine.
+func startSink(sink Sink, cfg config.SinkConfig) *sinkRunner {
+	size := cfg.QueueSize
+	if size <= 0 {
+		size = defaultSinkQueueSize
+	}
+	r := &sinkRunner{
+		sink:  sink,
+		kind:  cfg.Type,
+		queue: make(chan sinkMessage, size),
+	}
+	go r.run()
+	return r
+}
+
+func (r *sinkRunner) run() {
+	defer r.sink.Close()
+
+	for msg := range r.queue {
+		var err error
+		switch {
+		case msg.result != nil:
+			err = r.sink.SendResult(*msg.result)
+		case msg.command != nil:
+			err = r.sink.SendCommand(*msg.command)
+		case msg.event != nil:
+			err = r.sink.SendEvent(*msg.event)
+		case msg.frame != nil:
+			err = r.sink.SendFrame(*msg.frame)
+		}
+
+		if err != nil {
+			if r.failed.Add(1) == 1 {
+				log.Printf("[ERROR][Hub][Sink] %s: %v", r.sink.Name(), err)
+			}
+			r.lastErr.Store(err.Error())
+			continue
+		}
+		r.sent.Add(1)
+	}
+}
+
+// enqueue hands msg to the Sink without blocking.
+func (r *sinkRunner) enqueue(msg sinkMessage) {
+	select {
+	case r.queue <- msg:
+	default:
+		if r.dropped.Add(1) == 1 {
+			log.Printf("[ERROR][Hub][Sink] %s: queue full, dropping messages.", r.sink.Name())
+		}
+	}
+}
+
+// stats reports the accounting of the runner; Dropped includes the drops of the Sink itself.
+func (r *sinkRunner) stats() SinkStats {
+	lastErr, _ := r.lastErr.Load().(string)
+	dropped := r.dropped.Load()
+	if d, ok := r.sink.(dropCounter); ok {
+		dropped += d.Dropped()
+	}
+	return SinkStats{
+		Name:      r.sink.Name(),
+		Type:      r.kind,
+		Queued:    len(r.queue),
+		Capacity:  cap(r.queue),
+		Sent:      r.sent.Load(),
+		Failed:    r.failed.Load(),
+		Dropped:   dropped,
+		LastError: lastErr,
+	}
+}
+
+// sinkSet is the group of running Sinks the Hub fans out to.
+type sinkSet struct {
+	runners []*sinkRunner
+}
+
+// startSinks builds and starts every configured Sink, skipping the ones that fail to build.
+func startSinks(cfgs []config.SinkConfig) *sinkSet {
+	set := &sinkSet{}
+	for _, cfg := range cfgs {
+		sink, err := NewSink(cfg)
+		if err != nil {
+			log.Printf("[ERROR][Hub][Sink] Error creating %s sink: %v", cfg.Type, err)
+			continue
+		}
+		set.runners = append(set.runners, startSink(sink, cfg))
+		log.Printf("[INFO][Hub][Sink] %s running.", sink.Name())
+	}
+	return set
+}
+
+func (s *sinkSet) sendResult(result model.ResultData) {
+	for _, r := range s.runners {
+		r.enqueue(sinkMessage{result: &result})
+	}
+}
+
+func (s *sinkSet) sendCommand(cmd model.Command) {
+	for _, r := range s.runners {
+		r.enqueue(sinkMessage{command: &cmd})
+	}
+}
+
+func (s *sinkSet) sendEvent(event model.Event) {
+	for _, r := range s.runners {
+		r.enqueue(sinkMessage{event: &event})
+	}
+}
+
+func (s *sinkSet) sendFrame(frame model.SensorFrame) {
+	for _, r := range s.runners {
+		r.enqueue(sinkMessage{frame: &frame})
+	}
+}
+
+func (s *sinkSet) stats() []SinkStats {
+	stats := make([]SinkStats, 0, len(s.runners))
+	for _, r := range s.runners {
+		stats = append(stats, r.stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// sinkName returns the configured name of a Sink, or its type when unset.
+func sinkName(cfg config.SinkConfig) string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	return cfg.Type
+}
@@ -0,0 +1,168 @@
+package hub
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+// failingSink rejects every message, to exercise error accounting.
+type failingSink struct{}
+
+func (failingSink) Name() string                      { return "failing" }
+func (failingSink) SendResult(model.ResultData) error { return errors.New("boom") }
+func (failingSink) SendCommand(model.Command) error   { return errors.New("boom") }
+func (failingSink) SendEvent(model.Event) error       { return errors.New("boom") }
+func (failingSink) SendFrame(model.SensorFrame) error { return errors.New("boom") }
+func (failingSink) Close() error                      { return nil }
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSinkSetFanOut(t *testing.T) {
+	cfg := config.SinkConfig{Type: "memory"}
+	sink, err := NewSink(cfg)
+	if err != nil {
+		t.Fatalf("failed to create memory sink: %v", err)
+	}
+	memory := sink.(*MemorySink)
+
+	set := &sinkSet{runners: []*sinkRunner{
+		startSink(memory, cfg),
+		startSink(failingSink{}, config.SinkConfig{Type: "failing"}),
+	}}
+
+	set.sendResult(model.ResultData{VehicleID: "123", AverageSpeed: 42})
+	set.sendCommand(model.Command{Action: "start"})
+
+	waitFor(t, func() bool { return len(memory.Results()) == 1 && len(memory.Commands()) == 1 })
+	if got := memory.Results()[0].AverageSpeed; got != 42 {
+		t.Fatalf("AverageSpeed = %v, want 42", got)
+	}
+	if got := memory.Commands()[0].Action; got != "start" {
+		t.Fatalf("Action = %q, want start", got)
+	}
+
+	waitFor(t, func() bool { return set.stats()[0].Failed == 2 })
+	stats := set.stats()
+	if stats[0].Name != "failing" || stats[0].LastError != "boom" {
+		t.Fatalf("unexpected failing sink stats: %+v", stats[0])
+	}
+	if stats[1].Name != "memory" || stats[1].Sent != 2 || stats[1].Failed != 0 {
+		t.Fatalf("unexpected memory sink stats: %+v", stats[1])
+	}
+}
+
+func TestSinkRunnerDropsWhenFull(t *testing.T) {
+	// A runner with no goroutine never drains its queue.
+	r := &sinkRunner{sink: failingSink{}, queue: make(chan sinkMessage, 1)}
+	cmd := model.Command{Action: "start"}
+
+	r.enqueue(sinkMessage{command: &cmd})
+	r.enqueue(sinkMessage{command: &cmd})
+
+	if stats := r.stats(); stats.Queued != 1 || stats.Dropped != 1 {
+		t.Fatalf("Queued = %d, Dropped = %d, want 1 and 1", stats.Queued, stats.Dropped)
+	}
+}
+
+func TestWSSinkCountsSlowClientDrops(t *testing.T) {
+	// A client nobody reads from misses everything past its buffer.
+	conn := &websocket.Conn{}
+	wsClients.add(conn, false)
+	defer wsClients.remove(conn)
+
+	sink, err := NewSink(config.SinkConfig{Type: "ws"})
+	if err != nil {
+		t.Fatalf("failed to create ws sink: %v", err)
+	}
+	r := &sinkRunner{sink: sink, queue: make(chan sinkMessage, 1)}
+	for i := 0; i < wsClientBuffer+3; i++ {
+		if err := sink.SendResult(model.ResultData{VehicleID: "123"}); err != nil {
+			t.Fatalf("SendResult: %v", err)
+		}
+	}
+
+	if stats := r.stats(); stats.Dropped != 3 {
+		t.Fatalf("Dropped = %d, want the 3 results past the client buffer", stats.Dropped)
+	}
+}
+
+func TestWSRawFramesDoNotCrowdOutResults(t *testing.T) {
+	conn := &websocket.Conn{}
+	messages, frames := wsClients.add(conn, true)
+	defer wsClients.remove(conn)
+
+	sink, err := NewSink(config.SinkConfig{Type: "ws"})
+	if err != nil {
+		t.Fatalf("failed to create ws sink: %v", err)
+	}
+	r := &sinkRunner{sink: sink, queue: make(chan sinkMessage, 1)}
+
+	// A raw subscriber falling behind on frames misses frames only
+	for i := 0; i < wsFrameBuffer+5; i++ {
+		sink.SendFrame(model.SensorFrame{VehicleID: "123"})
+	}
+	sink.SendResult(model.ResultData{VehicleID: "123"})
+	sink.SendEvent(model.Event{Type: model.EventState})
+
+	if len(messages) != 2 || len(frames) != wsFrameBuffer {
+		t.Fatalf("%d messages and %d frames queued, want 2 and %d", len(messages), len(frames), wsFrameBuffer)
+	}
+	if stats := r.stats(); stats.Dropped != 5 {
+		t.Fatalf("Dropped = %d, want the 5 frames past the frame buffer", stats.Dropped)
+	}
+}
+
+func TestWSRawSubscription(t *testing.T) {
+	conn := &websocket.Conn{}
+	messages, frames := wsClients.add(conn, false)
+	defer wsClients.remove(conn)
+
+	// Not subscribed at connect time, no frames
+	if dropped := wsClients.broadcastRaw(model.SensorFrame{VehicleID: "123"}); dropped != 0 || len(frames) != 0 {
+		t.Fatalf("%d frames queued (%d dropped) before subscribing, want none", len(frames), dropped)
+	}
+
+	// Subscriptions are applied by the hub, not forwarded as Commands
+	if wsClients.handle(conn, model.Command{Action: "raw", Params: true}) {
+		t.Error("the raw Command was taken as a subscription")
+	}
+	if !wsClients.handle(conn, model.Command{Action: "Subscribe", Params: "RAW"}) {
+		t.Fatal("subscribe not handled")
+	}
+	wsClients.broadcastRaw(model.SensorFrame{VehicleID: "123"})
+	wsClients.broadcastRaw(model.SensorFrame{VehicleID: "123"})
+	if len(frames) != 2 {
+		t.Fatalf("%d frames queued after subscribing, want 2", len(frames))
+	}
+
+	// Unsubscribing discards the frames still buffered and stops new ones
+	wsClients.handle(conn, model.Command{Action: "unsubscribe", Params: "raw"})
+	wsClients.broadcastRaw(model.SensorFrame{VehicleID: "123"})
+	if len(frames) != 0 {
+		t.Errorf("%d frames queued after unsubscribing, want none", len(frames))
+	}
+
+	// An unknown topic is consumed and changes nothing
+	if !wsClients.handle(conn, model.Command{Action: "subscribe", Params: "video"}) {
+		t.Error("subscribe to an unknown topic forwarded")
+	}
+	wsClients.broadcastRaw(model.SensorFrame{VehicleID: "123"})
+	if len(frames) != 0 || len(messages) != 0 {
+		t.Errorf("%d frames and %d messages queued, want none", len(frames), len(messages))
+	}
+}
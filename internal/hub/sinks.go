@@ -0,0 +1,180 @@
+package hub
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+// fileSink appends every ResultData, Command, Event and raw SensorFrame as a JSON line to a file.
+type fileSink struct {
+	name    string
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func newFileSink(cfg config.SinkConfig) (Sink, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("file sink requires a path")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{name: sinkName(cfg), file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+func (s *fileSink) Name() string { return s.name }
+
+func (s *fileSink) SendResult(result model.ResultData) error { return s.encoder.Encode(result) }
+
+func (s *fileSink) SendCommand(cmd model.Command) error { return s.encoder.Encode(cmd) }
+
+func (s *fileSink) SendEvent(event model.Event) error { return s.encoder.Encode(event) }
+
+func (s *fileSink) SendFrame(frame model.SensorFrame) error { return s.encoder.Encode(frame) }
+
+func (s *fileSink) Close() error { return s.file.Close() }
+
+/*
+webhookSink POSTs every ResultData, Command, Event and raw SensorFrame as JSON to an HTTP endpoint.
+The X-Message-Type header tells the receiver which one it got ("result", "command", "event" or "frame").
+*/
+type webhookSink struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+func newWebhookSink(cfg config.SinkConfig) (Sink, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("webhook sink requires a url")
+	}
+	return &webhookSink{
+		name:   sinkName(cfg),
+		url:    cfg.URL,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+func (s *webhookSink) Name() string { return s.name }
+
+func (s *webhookSink) SendResult(result model.ResultData) error { return s.post("result", result) }
+
+func (s *webhookSink) SendCommand(cmd model.Command) error { return s.post("command", cmd) }
+
+func (s *webhookSink) SendEvent(event model.Event) error { return s.post("event", event) }
+
+func (s *webhookSink) SendFrame(frame model.SensorFrame) error { return s.post("frame", frame) }
+
+func (s *webhookSink) Close() error { return nil }
+
+func (s *webhookSink) post(kind string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshalling %s JSON: %w", kind, err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Message-Type", kind)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// MemorySink keeps every ResultData, Command, Event and raw SensorFrame it receives, for tests.
+type MemorySink struct {
+	name     string
+	mu       sync.Mutex
+	results  []model.ResultData
+	commands []model.Command
+	events   []model.Event
+	frames   []model.SensorFrame
+}
+
+func newMemorySink(cfg config.SinkConfig) (Sink, error) {
+	return &MemorySink{name: sinkName(cfg)}, nil
+}
+
+func (s *MemorySink) Name() string { return s.name }
+
+func (s *MemorySink) SendResult(result model.ResultData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, result)
+	return nil
+}
+
+func (s *MemorySink) SendCommand(cmd model.Command) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, cmd)
+	return nil
+}
+
+func (s *MemorySink) SendEvent(event model.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *MemorySink) SendFrame(frame model.SensorFrame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.frames = append(s.frames, frame)
+	return nil
+}
+
+func (s *MemorySink) Close() error { return nil }
+
+// Results returns a copy of the ResultData received so far.
+func (s *MemorySink) Results() []model.ResultData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]model.ResultData(nil), s.results...)
+}
+
+// Commands returns a copy of the Commands received so far.
+func (s *MemorySink) Commands() []model.Command {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]model.Command(nil), s.commands...)
+}
+
+// Events returns a copy of the Events received so far.
+func (s *MemorySink) Events() []model.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]model.Event(nil), s.events...)
+}
+
+// Frames returns a copy of the raw SensorFrames received so far.
+func (s *MemorySink) Frames() []model.SensorFrame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]model.SensorFrame(nil), s.frames...)
+}
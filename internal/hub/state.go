@@ -0,0 +1,53 @@
+package hub
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/vasyl-ks/TM-software-H11/internal/model"
+)
+
+// stateStore keeps the latest VehicleState of every vehicle, fed by "state" Events.
+type stateStore struct {
+	mu     sync.RWMutex
+	states map[string]model.VehicleState
+}
+
+func newStateStore() *stateStore {
+	return &stateStore{states: make(map[string]model.VehicleState)}
+}
+
+// update records the VehicleState carried by a "state" Event and ignores any other Event.
+func (s *stateStore) update(event model.Event) {
+	state, ok := event.Payload.(model.VehicleState)
+	if event.Type != model.EventState || !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[state.VehicleID] = state
+}
+
+// list returns the latest VehicleState of every vehicle, ordered by vehicle ID.
+func (s *stateStore) list() []model.VehicleState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]model.VehicleState, 0, len(s.states))
+	for _, state := range s.states {
+		list = append(list, state)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].VehicleID < list[j].VehicleID })
+	return list
+}
+
+// ServeHTTP exposes the latest VehicleState of every vehicle on /api/state.
+func (s *stateStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.list())
+}
@@ -11,42 +11,69 @@ import (
 )
 
 // CreateConnTCP establishes a TCP connection to the configured address and port.
-func CreateConnTCP() net.Conn {
+func CreateConnTCP() (net.Conn, error) {
 	address := fmt.Sprintf("127.0.0.1:%d", config.Hub.TCPPort)
 	conn, err := net.Dial("tcp", address)
 	if err != nil {
 		log.Println("[ERROR][Hub][TCP] Error connecting via TCP:", err)
-		panic(err)
+		return nil, err
 	}
 	log.Printf("[INFO][Hub][TCP] Established TCP connection from Hub to Consumer, on %s", address)
-	return conn
+	return conn, nil
 }
 
+// tcpSink sends Commands, Events and raw frames via TCP to the Consumer. ResultData is left to the UDP sink.
+type tcpSink struct {
+	name string
+	conn net.Conn
+}
+
+func newTCPSink(cfg config.SinkConfig) (Sink, error) {
+	conn, err := CreateConnTCP()
+	if err != nil {
+		return nil, err
+	}
+	return &tcpSink{name: sinkName(cfg), conn: conn}, nil
+}
+
+func (s *tcpSink) Name() string { return s.name }
+
+func (s *tcpSink) SendResult(model.ResultData) error { return nil }
+
 /*
-SendCommandToConsumer receives Command data from a channel,
-marshals it to JSON-encoded []byte
+SendCommand marshals a Command to JSON-encoded []byte
 and sends it via TCP to a localhost consumer.
 */
-func SendCommandToConsumer(conn net.Conn, inChan <-chan model.Command) {
-	defer conn.Close()
-	
-	// Receive Command from channel
-	for command := range inChan {
-		// Marshal ResultData to JSON-encoded []byte
-		data, err := json.Marshal(command)
-		if err != nil {
-			log.Println("[ERROR][Hub][TCP] Error marshalling WS command JSON:", err)
-			continue
-		}
-
-		// Append newline for message delimiting
-		data = append(data, '\n')
-
-		// Send JSON via TCP
-		_, err = conn.Write(data)
-		if err != nil {
-			log.Println("[ERROR][Hub][TCP] Error sending via TCP:", err)
-			break
-		}
+func (s *tcpSink) SendCommand(command model.Command) error {
+	return s.send("command", command)
+}
+
+// SendEvent sends an Event via TCP, the same way as a Command.
+func (s *tcpSink) SendEvent(event model.Event) error {
+	return s.send("event", event)
+}
+
+// SendFrame sends a raw SensorFrame via TCP, the same way as a Command.
+func (s *tcpSink) SendFrame(frame model.SensorFrame) error {
+	return s.send("frame", frame)
+}
+
+// send marshals v to a newline-delimited JSON message and writes it to the connection.
+func (s *tcpSink) send(kind string, v interface{}) error {
+	// Marshal to JSON-encoded []byte
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshalling %s JSON: %w", kind, err)
 	}
+
+	// Append newline for message delimiting
+	data = append(data, '\n')
+
+	// Send JSON via TCP
+	if _, err = s.conn.Write(data); err != nil {
+		return fmt.Errorf("sending via TCP: %w", err)
+	}
+	return nil
 }
+
+func (s *tcpSink) Close() error { return s.conn.Close() }
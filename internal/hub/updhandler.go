@@ -11,7 +11,7 @@ import (
 )
 
 // CreateConnUDP establishes a UDP connection to the configured address and port.
-func CreateConnUDP() *net.UDPConn {
+func CreateConnUDP() (*net.UDPConn, error) {
 	// Client address
 	address := net.UDPAddr{
 		IP:   net.ParseIP("127.0.0.1"),
@@ -20,34 +20,50 @@ func CreateConnUDP() *net.UDPConn {
 	conn, err := net.DialUDP("udp", nil, &address)
 	if err != nil {
 		log.Println("[ERROR][Hub][UDP] Error connecting via UDP", err)
-		panic(err)
+		return nil, err
 	}
 	log.Printf("[INFO][Hub][UDP] Established UDP connection from Hub to Consumer, on %s", fmt.Sprintf("%s:%d", address.IP, address.Port))
-	return conn
+	return conn, nil
 }
 
+// udpSink sends ResultData via UDP to the Consumer. Commands, Events and raw frames, too large for a datagram, are left to the TCP sink.
+type udpSink struct {
+	name string
+	conn *net.UDPConn
+}
+
+func newUDPSink(cfg config.SinkConfig) (Sink, error) {
+	conn, err := CreateConnUDP()
+	if err != nil {
+		return nil, err
+	}
+	return &udpSink{name: sinkName(cfg), conn: conn}, nil
+}
+
+func (s *udpSink) Name() string { return s.name }
+
 /*
-SendResultToConsumer receives ResultData from a channel,
-marshals it to JSON-encoded []byte
+SendResult marshals ResultData to JSON-encoded []byte
 and sends it via UDP to a localhost client.
 */
-func SendResultToConsumer(conn *net.UDPConn, inChan <-chan model.ResultData) {
-	defer conn.Close()
-	
-	// Receive ResultData from channel
-	for resultData := range inChan {
-		// Marshal ResultData to JSON-encoded []byte
-		data, err := json.Marshal(resultData)
-		if err != nil {
-			log.Println("[ERROR][Hub][UDP] Error marshalling WS result JSON", err)
-			continue
-		}
-
-		// Send JSON via UDP
-		_, err = conn.Write(data)
-		if err != nil {
-			log.Println("[ERROR][Hub][UDP] Error sending via UDP:", err)
-			continue
-		}
+func (s *udpSink) SendResult(resultData model.ResultData) error {
+	// Marshal ResultData to JSON-encoded []byte
+	data, err := json.Marshal(resultData)
+	if err != nil {
+		return fmt.Errorf("marshalling result JSON: %w", err)
+	}
+
+	// Send JSON via UDP
+	if _, err = s.conn.Write(data); err != nil {
+		return fmt.Errorf("sending via UDP: %w", err)
 	}
+	return nil
 }
+
+func (s *udpSink) SendCommand(model.Command) error { return nil }
+
+func (s *udpSink) SendEvent(model.Event) error { return nil }
+
+func (s *udpSink) SendFrame(model.SensorFrame) error { return nil }
+
+func (s *udpSink) Close() error { return s.conn.Close() }
@@ -5,11 +5,25 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/vasyl-ks/TM-software-H11/config"
 	"github.com/vasyl-ks/TM-software-H11/internal/model"
+	"github.com/vasyl-ks/TM-software-H11/internal/queue"
 )
 
+// wsClientBuffer is how many messages a slow Frontend client may lag behind before it misses some.
+const wsClientBuffer = 64
+
+// wsFrameBuffer is how many raw SensorFrames a slow raw subscriber may lag behind, apart from its other messages.
+const wsFrameBuffer = 64
+
+// wsDropWarnInterval is the least time between two warnings about messages slow Frontend clients missed.
+const wsDropWarnInterval = 5 * time.Second
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
@@ -28,9 +42,10 @@ func CreateConnWS(w http.ResponseWriter, r *http.Request) *websocket.Conn {
 /*
 ListenCommandWS listens for a command from the WebSocket
 parses it to a Go struct
-and forwards it to a channel.
+and forwards it to a queue.
+"subscribe" and "unsubscribe" Commands (params: "raw") switch the raw frames of this client instead (see wsClientSet.handle).
 */
-func ReceiveCommandFromFrontEnd(conn *websocket.Conn, outChan1 chan<- model.Command, outChan2 chan<- model.Command) {
+func ReceiveCommandFromFrontEnd(conn *websocket.Conn, outQueue *queue.Queue[model.Command]) {
 	defer conn.Close()
 
 	for {
@@ -55,27 +70,42 @@ func ReceiveCommandFromFrontEnd(conn *websocket.Conn, outChan1 chan<- model.Comm
 			continue
 		}
 
-		// Sends it to channel
-		outChan1 <- cmd
-		outChan2 <- cmd
+		// Subscriptions concern this client only
+		if wsClients.handle(conn, cmd) {
+			continue
+		}
+
+		// Sends it to queue
+		outQueue.Push(cmd)
 	}
 }
 
 /*
-SendResultToFrontEnd receives ResultData from a channel,
-marshals it to JSON-encoded []byte
-and sends it via WS to the WebSocket client.
+SendToFrontEnd receives ResultData and Event messages from a channel, and raw SensorFrames from another
+(which only receives while the client is subscribed to the raw stream), marshals them to JSON-encoded []byte
+and sends them via WS to the WebSocket client, until the message channel is closed.
 */
-func SendResultToFrontEnd(conn *websocket.Conn, inChan <-chan model.ResultData) {
+func SendToFrontEnd(conn *websocket.Conn, inChan <-chan interface{}, inFrameChan <-chan interface{}) {
 	defer func() {
 		conn.Close()
 		log.Printf("[INFO][Hub][WS] Writer closed connection: %s", conn.RemoteAddr())
 	}()
 	
-	// Receive ResultData from channel
-	for result := range inChan {
-		// Marshal ResultData to JSON-encoded []byte
-		data, err := json.Marshal(result)
+	for {
+		// Receive message from either channel
+		var msg interface{}
+		select {
+		case m, ok := <-inChan:
+			if !ok {
+				return
+			}
+			msg = m
+		case m := <-inFrameChan:
+			msg = m
+		}
+
+		// Marshal message to JSON-encoded []byte
+		data, err := json.Marshal(msg)
 		if err != nil {
 			log.Println("[ERROR][Hub][WS] Error marshalling WS result JSON:", err)
 			continue
@@ -92,7 +122,167 @@ func SendResultToFrontEnd(conn *websocket.Conn, inChan <-chan model.ResultData)
 			} else {
 				log.Printf("[ERROR][Hub][WS] Error sending via WS: %v", err) // Unexpected error
 			}
-			break
+			return
 		}
 	}
 }
+
+
+/*
+wsClient holds the channels the writer of a Frontend client reads from.
+Raw SensorFrames have a buffer of their own, so a busy raw stream never crowds out ResultData and Events.
+*/
+type wsClient struct {
+	messages chan interface{} // ResultData and Events
+	frames   chan interface{} // raw SensorFrames
+	raw      bool             // subscribed to the raw stream, guarded by the mutex of the set
+}
+
+// wsClientSet tracks the connected Frontend clients.
+type wsClientSet struct {
+	mu      sync.Mutex
+	clients map[*websocket.Conn]*wsClient
+}
+
+var wsClients = &wsClientSet{clients: make(map[*websocket.Conn]*wsClient)}
+
+// add registers a client, subscribed to the raw stream when raw is set, and returns the channels its writer must read from.
+func (c *wsClientSet) add(conn *websocket.Conn, raw bool) (messages, frames <-chan interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	client := &wsClient{messages: make(chan interface{}, wsClientBuffer), frames: make(chan interface{}, wsFrameBuffer), raw: raw}
+	c.clients[conn] = client
+	return client.messages, client.frames
+}
+
+/*
+handle applies a subscription Command of the client of conn and reports whether cmd was one:
+- "Subscribe raw" → the client receives the raw SensorFrames from now on.
+- "Unsubscribe raw" → it stops receiving them; frames still buffered for it are discarded.
+The raw stream itself is switched by the "raw" Command, for every client.
+*/
+func (c *wsClientSet) handle(conn *websocket.Conn, cmd model.Command) bool {
+	var raw bool
+	switch strings.ToLower(cmd.Action) {
+	case "subscribe":
+		raw = true
+	case "unsubscribe":
+	default:
+		return false
+	}
+	if topic, _ := cmd.Params.(string); !strings.EqualFold(topic, "raw") {
+		log.Printf("[ERROR][Hub][WS] Unknown %s topic %v.", cmd.Action, cmd.Params)
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	client, ok := c.clients[conn]
+	if !ok {
+		return true
+	}
+	client.raw = raw
+	for !raw && len(client.frames) > 0 {
+		select {
+		case <-client.frames:
+		default:
+		}
+	}
+	log.Printf("[INFO][Hub][WS] Client subscribed to raw frames: %t.", raw)
+	return true
+}
+
+// remove unregisters a client and closes its message channel, which stops its writer.
+func (c *wsClientSet) remove(conn *websocket.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.clients[conn]; ok {
+		close(client.messages)
+		delete(c.clients, conn)
+	}
+}
+
+// broadcast hands msg to every client without blocking on slow ones, and returns how many of them missed it.
+func (c *wsClientSet) broadcast(msg interface{}) (dropped int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, client := range c.clients {
+		select {
+		case client.messages <- msg:
+		default:
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// broadcastRaw hands msg to every client subscribed to the raw stream, through its frame buffer, the same way as broadcast.
+func (c *wsClientSet) broadcastRaw(msg interface{}) (dropped int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, client := range c.clients {
+		if !client.raw {
+			continue
+		}
+		select {
+		case client.frames <- msg:
+		default:
+			dropped++
+		}
+	}
+	return dropped
+}
+
+/*
+wsSink broadcasts ResultData and Events to every connected Frontend client, and raw frames to the subscribed ones.
+Messages a slow client misses are counted in its SinkStats, with a warning at most every wsDropWarnInterval.
+*/
+type wsSink struct {
+	name    string
+	dropped atomic.Uint64
+
+	// Only touched by the sinkRunner goroutine
+	warnedAt      time.Time
+	warnedDropped uint64
+}
+
+func newWSSink(cfg config.SinkConfig) (Sink, error) {
+	return &wsSink{name: sinkName(cfg)}, nil
+}
+
+func (s *wsSink) Name() string { return s.name }
+
+func (s *wsSink) SendResult(result model.ResultData) error {
+	s.countDrops(wsClients.broadcast(result))
+	return nil
+}
+
+func (s *wsSink) SendCommand(model.Command) error { return nil }
+
+func (s *wsSink) SendEvent(event model.Event) error {
+	s.countDrops(wsClients.broadcast(event))
+	return nil
+}
+
+func (s *wsSink) SendFrame(frame model.SensorFrame) error {
+	s.countDrops(wsClients.broadcastRaw(frame))
+	return nil
+}
+
+func (s *wsSink) Close() error { return nil }
+
+// Dropped returns how many messages slow clients missed.
+func (s *wsSink) Dropped() uint64 { return s.dropped.Load() }
+
+// countDrops accounts for n messages missed by slow clients.
+func (s *wsSink) countDrops(n int) {
+	if n == 0 {
+		return
+	}
+	total := s.dropped.Add(uint64(n))
+	if time.Since(s.warnedAt) < wsDropWarnInterval {
+		return
+	}
+	log.Printf("[WARN][Hub][WS] %s: slow clients missed %d messages (%d in total).", s.name, total-s.warnedDropped, total)
+	s.warnedAt, s.warnedDropped = time.Now(), total
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Alert states
+const (
+	AlertRaised  = "raised"  // the condition of the rule held for its duration
+	AlertCleared = "cleared" // the value moved back past the threshold by the hysteresis of the rule
+)
+
+/*
+Alert represents a threshold alarm of an alert rule, carried by an "alert" Event when it is raised and when it clears,
+containing the rule and its condition, the value that raised or cleared it
+and the time the condition started holding.
+*/
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Severity  string    `json:"severity"`
+	State     string    `json:"state"`
+	Source    string    `json:"source"` // "batch", "sample" or the name of a window
+	Field     string    `json:"field"`
+	Op        string    `json:"op"`
+	Threshold float64   `json:"threshold"`
+	Value     float64   `json:"value"`
+	Since     time.Time `json:"since"`
+}
@@ -0,0 +1,24 @@
+package model
+
+// Anomaly checks
+const (
+	AnomalyZScore = "zscore" // the batch average is far from its baseline
+	AnomalySpread = "spread" // the standard deviation within the batch is far from its baseline, e.g. on spikes
+	AnomalyRate   = "rate"   // the batch average changed faster than its baseline rate, or than the configured limit
+)
+
+/*
+Anomaly represents a channel of a vehicle that departed from its usual behavior, carried by an "anomaly" Event,
+containing the check that flagged it, the offending value (a batch average, the standard deviation within the batch
+for spread checks or the change per second of the average for rate checks),
+the baseline it was compared against and how far it was from it.
+*/
+type Anomaly struct {
+	Check    string  `json:"check"`
+	Channel  string  `json:"channel"`
+	Value    float64 `json:"value"`
+	Baseline float64 `json:"baseline"`        // EWMA mean before this batch
+	StdDev   float64 `json:"stdDev"`          // EWMA standard deviation before this batch
+	ZScore   float64 `json:"zScore"`          // (value - baseline) / stdDev
+	Limit    float64 `json:"limit,omitempty"` // the configured maxRate it exceeded
+}
@@ -3,8 +3,12 @@ package model
 /*
 Command represents an instruction received from the Frontend,
 containing an action name and optional parameters.
+VehicleID addresses a single vehicle; when empty, the Command applies to every vehicle.
+ScheduleID is set only when the Command was dispatched by the Hub scheduler.
 */
 type Command struct {
-	Action string      `json:"action"`
-	Params interface{} `json:"params,omitempty"`
-}
\ No newline at end of file
+	Action     string      `json:"action"`
+	Params     interface{} `json:"params,omitempty"`
+	VehicleID  string      `json:"vehicleID,omitempty"`
+	ScheduleID string      `json:"scheduleID,omitempty"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Event types
+const (
+	EventState      = "state"
+	EventFault      = "fault"
+	EventReplay     = "replay"
+	EventTransition = "transition"
+	EventRejected   = "rejected"
+	EventAlert      = "alert"
+	EventAnomaly    = "anomaly"
+	EventTrip       = "trip"
+)
+
+/*
+Event represents a notification raised by the pipeline, such as a vehicle state change or a fault,
+containing its type, a human-readable message, an optional typed payload
+and indemnifications such as the vehicle ID and the time it was created.
+*/
+type Event struct {
+	Type      string      `json:"type"`
+	VehicleID string      `json:"vehicleID"`
+	Message   string      `json:"message,omitempty"`
+	Payload   interface{} `json:"payload,omitempty"`
+	CreatedAt time.Time   `json:"createdAt"`
+}
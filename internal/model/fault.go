@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Fault types
+const (
+	FaultDropout = "dropout" // the channel reads 0; on "all" no SensorData is emitted
+	FaultStuck   = "stuck"   // the channel reads Value
+	FaultSpike   = "spike"   // the channel jumps by ±Value with Probability per sample
+	FaultNaN     = "nan"     // the channel reads NaN
+	FaultDrift   = "drift"   // the channel drifts away by Value units per second
+	FaultDelay   = "delay"   // the channel reads its value from Value seconds ago
+)
+
+/*
+Fault represents a sensor fault injected into a simulated channel,
+containing its type, the affected channel ("speed", "pressure", "temperature" or "all"),
+its type-specific parameters and the period it is active.
+A nil Value means the default of the type, a zero ExpiresAt that the Fault stays active until cleared.
+*/
+type Fault struct {
+	Type        string    `json:"type"`
+	Channel     string    `json:"channel"`
+	Value       *float64  `json:"value,omitempty"`
+	Probability float64   `json:"probability,omitempty"`
+	StartedAt   time.Time `json:"startedAt"`
+	ExpiresAt   time.Time `json:"expiresAt,omitzero"`
+}
@@ -0,0 +1,16 @@
+package model
+
+/*
+ReplayStatus represents the progress of a replayed recording,
+containing its source, the position within it in seconds and records,
+and whether it loops or has finished.
+*/
+type ReplayStatus struct {
+	Source   string  `json:"source"`
+	Index    int     `json:"index"`    // next record to play
+	Records  int     `json:"records"`  // records in the recording
+	Position float64 `json:"position"` // seconds since the start of the recording
+	Length   float64 `json:"length"`   // seconds
+	Loop     bool    `json:"loop"`
+	Finished bool    `json:"finished"`
+}
@@ -4,20 +4,61 @@ import "time"
 
 /*
 ResultData represents statistics for a batch of SensorData,
-containing average, minimum, and maximum values for both speed, temperature and pressure
-and indemnifications such as its ID and the time it was generated and processed.
+containing average, minimum, and maximum values for speed, temperature, pressure
+and the battery channels (voltage, current, state of charge and cell temperature),
+the position and distance to the end of the track at its last reading,
+the number of readings and the distribution statistics of every channel (Stats, by channel name),
+data-quality flags (readings expected from the sensor interval, readings out of the configured ranges,
+and Stale for a gap result emitted while no reading arrived)
+and indemnifications such as its ID, the aggregation window it belongs to (empty for the processor batches),
+the trip of its last reading taken in one (empty outside trips) and the time it was generated and processed.
 */
 type ResultData struct {
-	AverageSpeed       float32
-	MinimumSpeed       float32
-	MaximumSpeed       float32
-	AverageTemperature float32
-	MinimumTemperature float32
-	MaximumTemperature float32
-	AveragePressure    float32
-	MinimumPressure    float32
-	MaximumPressure    float32
-	VehicleID          string
-	CreatedAt          time.Time
-	ProcessedAt        time.Time
+	AverageSpeed              float32
+	MinimumSpeed              float32
+	MaximumSpeed              float32
+	AverageTemperature        float32
+	MinimumTemperature        float32
+	MaximumTemperature        float32
+	AveragePressure           float32
+	MinimumPressure           float32
+	MaximumPressure           float32
+	AverageBatteryVoltage     float32
+	MinimumBatteryVoltage     float32
+	MaximumBatteryVoltage     float32
+	AverageBatteryCurrent     float32
+	MinimumBatteryCurrent     float32
+	MaximumBatteryCurrent     float32
+	AverageBatterySoC         float32
+	MinimumBatterySoC         float32
+	MaximumBatterySoC         float32
+	AverageBatteryTemperature float32
+	MinimumBatteryTemperature float32
+	MaximumBatteryTemperature float32
+	Position                  float32
+	DistanceToEnd             float32
+	Count                     int
+	ExpectedCount             float32
+	OutOfRangeCount           int
+	Stale                     bool
+	Stats                     map[string]ChannelStats
+	Window                    string `json:",omitempty"`
+	TripID                    string `json:",omitempty"`
+	VehicleID                 string
+	CreatedAt                 time.Time
+	ProcessedAt               time.Time
+}
+
+/*
+ChannelStats represents the distribution of one channel over a batch of SensorData.
+NaN readings are left out, so Count may be lower than the readings of the batch.
+*/
+type ChannelStats struct {
+	Count       int
+	StdDev      float32
+	Median      float32
+	Percentiles map[string]float32 `json:",omitempty"` // by name, e.g. "p95"
+	First       float32
+	Last        float32
+	Rate        float32 // change per second between the first and the last reading
 }
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Status values of a ScheduledCommand.
+const (
+	SchedulePending   = "pending"
+	ScheduleExecuted  = "executed"
+	ScheduleCancelled = "cancelled"
+)
+
+/*
+ScheduledCommand represents a Command queued in the Hub for delayed execution,
+containing the Command itself, the time it must be dispatched at
+and indemnifications such as its ID, status and the time it was created and executed.
+*/
+type ScheduledCommand struct {
+	ID         string     `json:"id"`
+	Command    Command    `json:"command"`
+	ExecuteAt  time.Time  `json:"executeAt"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExecutedAt *time.Time `json:"executedAt,omitempty"`
+}
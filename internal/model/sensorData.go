@@ -4,13 +4,21 @@ import "time"
 
 /*
 SensorData represents a single sensor reading,
-containing speed, pressure and temperature values
-and indemnifications such as its ID and the time it was generated.
+containing speed, pressure, temperature and battery (voltage, current, state of charge, cell temperature) values,
+the position on the track and the distance left to its end (in meters),
+and indemnifications such as its ID, the trip it was taken in (empty outside trips) and the time it was generated.
 */
 type SensorData struct {
-	Speed       float32
-	Pressure    float32
-	Temperature float32
-	VehicleID   string
-	CreatedAt time.Time
+	Speed              float32
+	Pressure           float32
+	Temperature        float32
+	BatteryVoltage     float32
+	BatteryCurrent     float32
+	BatterySoC         float32
+	BatteryTemperature float32
+	Position           float32
+	DistanceToEnd      float32
+	VehicleID          string
+	TripID             string `json:",omitempty"`
+	CreatedAt          time.Time
 }
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+/*
+SensorFrame represents a batch of raw SensorData readings of a vehicle, streamed alongside its ResultData,
+containing the readings in the order they were taken and the time of the first and last one.
+The readings leave out their VehicleID, which the frame carries, and NaN or infinite values are sent as null.
+*/
+type SensorFrame struct {
+	VehicleID string       `json:"vehicleID"`
+	Samples   []SensorData `json:"samples"`
+	StartedAt time.Time    `json:"startedAt"` // first reading
+	CreatedAt time.Time    `json:"createdAt"` // last reading
+}
+
+// frameSample is the JSON form of a reading within a SensorFrame.
+type frameSample struct {
+	Speed              *float32
+	Pressure           *float32
+	Temperature        *float32
+	BatteryVoltage     *float32
+	BatteryCurrent     *float32
+	BatterySoC         *float32
+	BatteryTemperature *float32
+	Position           *float32
+	DistanceToEnd      *float32
+	CreatedAt          time.Time
+}
+
+type frameJSON struct {
+	VehicleID string        `json:"vehicleID"`
+	Samples   []frameSample `json:"samples"`
+	StartedAt time.Time     `json:"startedAt"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+func (f SensorFrame) MarshalJSON() ([]byte, error) {
+	out := frameJSON{VehicleID: f.VehicleID, Samples: make([]frameSample, len(f.Samples)), StartedAt: f.StartedAt, CreatedAt: f.CreatedAt}
+	for i, d := range f.Samples {
+		out.Samples[i] = frameSample{
+			Speed:              finite(d.Speed),
+			Pressure:           finite(d.Pressure),
+			Temperature:        finite(d.Temperature),
+			BatteryVoltage:     finite(d.BatteryVoltage),
+			BatteryCurrent:     finite(d.BatteryCurrent),
+			BatterySoC:         finite(d.BatterySoC),
+			BatteryTemperature: finite(d.BatteryTemperature),
+			Position:           finite(d.Position),
+			DistanceToEnd:      finite(d.DistanceToEnd),
+			CreatedAt:          d.CreatedAt,
+		}
+	}
+	return json.Marshal(out)
+}
+
+func (f *SensorFrame) UnmarshalJSON(data []byte) error {
+	var in frameJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*f = SensorFrame{VehicleID: in.VehicleID, Samples: make([]SensorData, len(in.Samples)), StartedAt: in.StartedAt, CreatedAt: in.CreatedAt}
+	for i, s := range in.Samples {
+		f.Samples[i] = SensorData{
+			Speed:              orNaN(s.Speed),
+			Pressure:           orNaN(s.Pressure),
+			Temperature:        orNaN(s.Temperature),
+			BatteryVoltage:     orNaN(s.BatteryVoltage),
+			BatteryCurrent:     orNaN(s.BatteryCurrent),
+			BatterySoC:         orNaN(s.BatterySoC),
+			BatteryTemperature: orNaN(s.BatteryTemperature),
+			Position:           orNaN(s.Position),
+			DistanceToEnd:      orNaN(s.DistanceToEnd),
+			VehicleID:          in.VehicleID,
+			CreatedAt:          s.CreatedAt,
+		}
+	}
+	return nil
+}
+
+// finite returns v, or nil when it cannot be encoded in JSON.
+func finite(v float32) *float32 {
+	if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+		return nil
+	}
+	return &v
+}
+
+// orNaN returns the value of a decoded reading, or NaN when it was null.
+func orNaN(v *float32) float32 {
+	if v == nil {
+		return float32(math.NaN())
+	}
+	return *v
+}
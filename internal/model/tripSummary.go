@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+/*
+TripSummary represents a trip of a vehicle, from an accepted "start" Command to the "stop" (or "reset") that ended it,
+containing its duration in seconds and distance in meters, the maximum and average speed and the peak temperature
+and pressure read during it, the mode changes and the Commands issued to the vehicle (including the rejected ones),
+and indemnifications such as its ID, stamped on the SensorData and ResultData of the trip.
+*/
+type TripSummary struct {
+	TripID          string    `json:"tripID"`
+	VehicleID       string    `json:"vehicleID"`
+	StartedAt       time.Time `json:"startedAt"`
+	EndedAt         time.Time `json:"endedAt"`
+	EndedBy         string    `json:"endedBy"`  // the Command that ended the trip
+	Duration        float64   `json:"duration"` // seconds
+	Distance        float64   `json:"distance"` // meters
+	MaxSpeed        float32   `json:"maxSpeed"`
+	AverageSpeed    float32   `json:"averageSpeed"`
+	PeakTemperature float32   `json:"peakTemperature"`
+	PeakPressure    float32   `json:"peakPressure"`
+	ModeChanges     int       `json:"modeChanges"`
+	Commands        int       `json:"commands"`
+	Rejected        int       `json:"rejected"`
+	Samples         int       `json:"samples"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// Control states of a vehicle
+const (
+	StateIdle             = "idle"             // powered down, at rest
+	StateReady            = "ready"            // started, waiting for a target speed
+	StateRunning          = "running"          // driving toward its target speed
+	StateBraking          = "braking"          // stopping to rest, then idle
+	StateEmergencyStopped = "emergencyStopped" // full braking, until reset
+	StateFault            = "fault"            // safety-critical fault, braking until reset
+)
+
+/*
+VehicleState represents a snapshot of a simulated vehicle,
+containing its control state, speeds and active faults
+and indemnifications such as its ID and the time it was taken.
+*/
+type VehicleState struct {
+	VehicleID     string    `json:"vehicleID"`
+	State         string    `json:"state"`
+	Started       bool      `json:"started"` // ready or running
+	Mode          string    `json:"mode"`
+	Speed         float32   `json:"speed"`
+	TargetSpeed   float32   `json:"targetSpeed"`
+	SpeedError    float32   `json:"speedError"`        // targetSpeed - speed, as seen by the speed controller
+	Throttle      float32   `json:"throttle"`          // speed controller output: > 0 traction, < 0 braking
+	Position      float64   `json:"position"`          // m from the start of the track
+	DistanceToEnd float64   `json:"distanceToEnd"`     // m, 0 on an open track
+	SpeedLimit    float32   `json:"speedLimit"`        // km/h enforced by the track at this position, 0 when none
+	Segment       string    `json:"segment,omitempty"` // segment the vehicle is in
+	Station       string    `json:"station,omitempty"` // last station reached
+	Faults        []Fault   `json:"faults"`
+	Trip          string    `json:"trip,omitempty"` // open trip
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// StateTransition represents a change of the control state of a vehicle and what caused it.
+type StateTransition struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Cause string `json:"cause"` // the Command action, or the condition for automatic transitions
+}
+
+// CommandRejection represents a Command a vehicle refused in its current control state.
+type CommandRejection struct {
+	Command Command `json:"command"`
+	State   string  `json:"state"`
+	Reason  string  `json:"reason"`
+}
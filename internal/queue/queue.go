@@ -0,0 +1,207 @@
+package queue
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+)
+
+/*
+Queue is a bounded channel between two pipeline stages with an explicit overflow policy.
+Its capacity and policy come from config.Pipeline.Channels[name]; an undeclared channel
+is unbuffered and blocking, like a plain make(chan T), and so is a channel declared without capacity.
+- Producers call Push, consumers read from Out.
+- Every Queue registers itself so its depth, drops and stalls can be reported.
+*/
+type Queue[T any] struct {
+	name     string
+	policy   string
+	ch       chan T
+	pushed   atomic.Uint64
+	dropped  atomic.Uint64
+	stalled  atomic.Uint64
+	maxDepth atomic.Int64
+}
+
+// Stats reports the backpressure accounting of a Queue.
+type Stats struct {
+	Name     string `json:"name"`
+	Policy   string `json:"policy"`
+	Depth    int    `json:"depth"`
+	Capacity int    `json:"capacity"`
+	MaxDepth int    `json:"maxDepth"`
+	Pushed   uint64 `json:"pushed"`
+	Dropped  uint64 `json:"dropped"`
+	Stalled  uint64 `json:"stalled"` // pushes that had to wait for room (block policy)
+}
+
+// statser is the type-independent view of a Queue kept in the registry.
+type statser interface {
+	Stats() Stats
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []statser
+)
+
+// New creates the Queue configured under name and registers it for reporting.
+func New[T any](name string) *Queue[T] {
+	return newQueue[T](name, name)
+}
+
+/*
+NewInstance creates one of several Queues sharing the configuration of name, e.g. one per vehicle.
+It is reported as "name/instance".
+*/
+func NewInstance[T any](name, instance string) *Queue[T] {
+	return newQueue[T](name, name+"/"+instance)
+}
+
+func newQueue[T any](name, reportName string) *Queue[T] {
+	c, ok := config.Pipeline.Channels[name]
+	if !ok {
+		c = config.ChannelConfig{Capacity: 0, Overflow: config.OverflowBlock}
+	}
+	// Without capacity there is nothing to drop: dropOldest would spin and dropNewest drop everything
+	if c.Capacity <= 0 {
+		c = config.ChannelConfig{Capacity: 0, Overflow: config.OverflowBlock}
+	}
+
+	q := &Queue[T]{
+		name:   reportName,
+		policy: c.Overflow,
+		ch:     make(chan T, c.Capacity),
+	}
+
+	registryMu.Lock()
+	registry = append(registry, q)
+	registryMu.Unlock()
+
+	return q
+}
+
+// Out returns the channel consumers read from.
+func (q *Queue[T]) Out() <-chan T {
+	return q.ch
+}
+
+// Push hands v to the consumer following the overflow policy and reports whether it was queued.
+func (q *Queue[T]) Push(v T) bool {
+	q.pushed.Add(1)
+	defer q.observeDepth()
+
+	// Fast path: there is room
+	select {
+	case q.ch <- v:
+		return true
+	default:
+	}
+
+	switch q.policy {
+	case config.OverflowDropNewest:
+		q.dropped.Add(1)
+		return false
+
+	case config.OverflowDropOldest:
+		for {
+			// Discard the oldest value, then retry, as another producer may have taken the room
+			select {
+			case <-q.ch:
+				q.dropped.Add(1)
+			default:
+			}
+			select {
+			case q.ch <- v:
+				return true
+			default:
+			}
+		}
+
+	default:
+		q.stalled.Add(1)
+		q.ch <- v
+		return true
+	}
+}
+
+// Close closes the channel; Push must not be called afterwards.
+func (q *Queue[T]) Close() {
+	close(q.ch)
+}
+
+// observeDepth records the highest depth seen so far.
+func (q *Queue[T]) observeDepth() {
+	depth := int64(len(q.ch))
+	for {
+		current := q.maxDepth.Load()
+		if depth <= current || q.maxDepth.CompareAndSwap(current, depth) {
+			return
+		}
+	}
+}
+
+// Stats returns a snapshot of the Queue accounting.
+func (q *Queue[T]) Stats() Stats {
+	return Stats{
+		Name:     q.name,
+		Policy:   q.policy,
+		Depth:    len(q.ch),
+		Capacity: cap(q.ch),
+		MaxDepth: int(q.maxDepth.Load()),
+		Pushed:   q.pushed.Load(),
+		Dropped:  q.dropped.Load(),
+		Stalled:  q.stalled.Load(),
+	}
+}
+
+// Snapshot returns the Stats of every registered Queue, ordered by name.
+func Snapshot() []Stats {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	stats := make([]Stats, 0, len(registry))
+	for _, q := range registry {
+		stats = append(stats, q.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+/*
+Report logs, every config.Pipeline.ReportInterval, the queues that are saturated:
+the ones that dropped or stalled since the last report, or are at least 80% full.
+It does nothing when no interval is configured.
+*/
+func Report() {
+	interval := config.Pipeline.ReportInterval
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Println("[INFO][Queue][Report] Running.")
+
+	last := make(map[string]Stats)
+	for range ticker.C {
+		for _, s := range Snapshot() {
+			prev := last[s.Name]
+			last[s.Name] = s
+
+			dropped := s.Dropped - prev.Dropped
+			stalled := s.Stalled - prev.Stalled
+			full := s.Capacity > 0 && s.Depth*5 >= s.Capacity*4
+			if dropped == 0 && stalled == 0 && !full {
+				continue
+			}
+			log.Printf("[WARN][Queue][Report] %s saturated: depth %d/%d, %d dropped and %d stalled in the last %s.",
+				s.Name, s.Depth, s.Capacity, dropped, stalled, interval)
+		}
+	}
+}
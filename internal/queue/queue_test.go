@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vasyl-ks/TM-software-H11/config"
+)
+
+func TestQueueOverflowPolicies(t *testing.T) {
+	config.Pipeline.Channels = map[string]config.ChannelConfig{
+		"test.dropNewest": {Capacity: 2, Overflow: config.OverflowDropNewest},
+		"test.dropOldest": {Capacity: 2, Overflow: config.OverflowDropOldest},
+	}
+
+	tests := []struct {
+		name string
+		want []int
+	}{
+		{"test.dropNewest", []int{1, 2}},
+		{"test.dropOldest", []int{2, 3}},
+	}
+
+	for _, tt := range tests {
+		q := New[int](tt.name)
+		for v := 1; v <= 3; v++ {
+			q.Push(v)
+		}
+
+		stats := q.Stats()
+		if stats.Pushed != 3 || stats.Dropped != 1 || stats.Depth != 2 || stats.MaxDepth != 2 {
+			t.Fatalf("%s: unexpected stats %+v", tt.name, stats)
+		}
+		for _, want := range tt.want {
+			if got := <-q.Out(); got != want {
+				t.Fatalf("%s: got %d, want %d", tt.name, got, want)
+			}
+		}
+	}
+}
+
+func TestQueueUndeclaredIsUnbufferedAndBlocking(t *testing.T) {
+	config.Pipeline.Channels = nil
+
+	q := New[int]("test.undeclared")
+	done := make(chan struct{})
+	go func() {
+		q.Push(1)
+		close(done)
+	}()
+
+	if got := <-q.Out(); got != 1 {
+		t.Fatalf("got %d, want 1", got)
+	}
+	<-done
+
+	if stats := q.Stats(); stats.Capacity != 0 || stats.Policy != config.OverflowBlock || stats.Dropped != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestQueueWithoutCapacityBlocks(t *testing.T) {
+	defer func(channels map[string]config.ChannelConfig) { config.Pipeline.Channels = channels }(config.Pipeline.Channels)
+	config.Pipeline.Channels = map[string]config.ChannelConfig{
+		"test.zeroDropNewest": {Capacity: 0, Overflow: config.OverflowDropNewest},
+		"test.zeroDropOldest": {Capacity: 0, Overflow: config.OverflowDropOldest},
+	}
+
+	// Dropping policies cannot drop from an unbuffered channel, so they wait for the consumer like block
+	for name := range config.Pipeline.Channels {
+		q := New[int](name)
+		done := make(chan bool)
+		go func() { done <- q.Push(1) }()
+
+		select {
+		case <-done:
+			t.Fatalf("%s: Push returned without a consumer", name)
+		case <-time.After(20 * time.Millisecond):
+		}
+		if got := <-q.Out(); got != 1 {
+			t.Fatalf("%s: got %d, want 1", name, got)
+		}
+		if !<-done {
+			t.Fatalf("%s: Push reported the value dropped", name)
+		}
+		if stats := q.Stats(); stats.Policy != config.OverflowBlock || stats.Dropped != 0 || stats.Stalled != 1 {
+			t.Fatalf("%s: unexpected stats %+v", name, stats)
+		}
+	}
+}